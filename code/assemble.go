@@ -0,0 +1,56 @@
+package code
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assemble 함수는 한 줄에 명령어 하나씩 적은 텍스트를 바이트코드로 변환합니다.
+// 각 줄은 "OpConstant 0" 처럼 Opcode 이름과 10진수 피연산자로 이루어집니다.
+// Instructions.String 의 출력처럼 줄 앞에 오프셋("0003 OpAdd")이 붙어 있으면 그 오프셋은
+// 실제 위치와 일치해야 합니다. 빈 줄은 무시합니다.
+func Assemble(text string) (Instructions, error) {
+	out := Instructions{}
+
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// 역어셈블 결과를 그대로 붙여 넣을 수 있도록 맨 앞의 오프셋을 허용합니다.
+		if offset, err := strconv.Atoi(fields[0]); err == nil {
+			if offset != len(out) {
+				return nil, fmt.Errorf("line %d: offset %04d does not match position %04d",
+					i+1, offset, len(out))
+			}
+			fields = fields[1:]
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("line %d: missing opcode", i+1)
+			}
+		}
+
+		op, err := LookupByName(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+
+		operands := make([]int, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			operand, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid operand %q", i+1, f)
+			}
+			operands = append(operands, operand)
+		}
+
+		ins, err := MakeSafe(op, operands...)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		out = append(out, ins...)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package code
+
+import "testing"
+
+func TestAssemble(t *testing.T) {
+	text := `
+	OpConstant 0
+	OpConstant 1
+	OpAdd
+	OpClosure 2 0
+	OpPop
+	`
+
+	expected := Instructions{}
+	for _, ins := range [][]byte{
+		Make(OpConstant, 0),
+		Make(OpConstant, 1),
+		Make(OpAdd),
+		Make(OpClosure, 2, 0),
+		Make(OpPop),
+	} {
+		expected = append(expected, ins...)
+	}
+
+	actual, err := Assemble(text)
+	if err != nil {
+		t.Fatalf("Assemble failed: %s", err)
+	}
+
+	if string(actual) != string(expected) {
+		t.Errorf("wrong instructions.\nwant=%q\ngot =%q", expected, actual)
+	}
+}
+
+func TestAssembleRoundTrip(t *testing.T) {
+	text := `0000 OpConstant 0
+0003 OpConstant 1
+0006 OpAdd
+0007 OpCall 2
+0009 OpJumpNotTruthy 0
+0012 OpPop
+`
+
+	ins, err := Assemble(text)
+	if err != nil {
+		t.Fatalf("Assemble failed: %s", err)
+	}
+
+	if ins.String() != text {
+		t.Errorf("round trip failed.\nwant=%q\ngot =%q", text, ins.String())
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"OpNope", `line 1: opcode "OpNope" undefined`},
+		{"OpAdd\nOpConstant x", `line 2: invalid operand "x"`},
+		{"OpConstant", "line 1: wrong number of operands for OpConstant. want=1, got=0"},
+		{"OpCall 256", "line 1: operand 256 overflows 1-byte width"},
+		{"0000 OpAdd\n0002 OpPop", "line 2: offset 0002 does not match position 0001"},
+	}
+
+	for _, tt := range tests {
+		_, err := Assemble(tt.text)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", tt.text)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"sort"
 )
 
 // Instruction 은 컴파일된 바이트코드 명령어의 기본 단위입니다.
@@ -20,6 +21,79 @@ const (
 	// OpConstant 는 상수를 스택에 푸시하는 명령어입니다.
 	OpConstant Opcode = iota
 	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	// OpPop 은 스택 최상단의 값을 꺼내 버리는 명령어입니다.
+	OpPop
+	// OpTrue 와 OpFalse 는 불리언 값을 상수 풀을 거치지 않고 스택에 푸시합니다.
+	OpTrue
+	OpFalse
+	// 비교 연산자입니다. '<' 는 피연산자 순서를 바꿔 OpGreaterThan 으로 컴파일합니다.
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	// 전위 연산자입니다. OpMinus 는 '-', OpBang 은 '!' 에 해당합니다.
+	OpMinus
+	OpBang
+	// 점프 명령어입니다. 피연산자는 점프할 명령어의 오프셋입니다.
+	// OpJumpNotTruthy 는 스택 최상단 값이 참이 아닐 때만 점프합니다.
+	OpJumpNotTruthy
+	OpJump
+	// OpNull 은 값이 없음을 나타내는 Null 을 스택에 푸시합니다.
+	OpNull
+	// 전역 바인딩 명령어입니다. 피연산자는 전역 심볼의 인덱스입니다.
+	OpGetGlobal
+	OpSetGlobal
+	// OpArray 는 스택의 최상단 N개 값으로 배열을 만듭니다. 피연산자는 원소의 개수입니다.
+	OpArray
+	// OpHash 는 스택의 최상단 N개 값으로 해시를 만듭니다. 피연산자는 키와 값의 총 개수입니다.
+	OpHash
+	// OpIndex 는 스택의 두 값(피인덱스 대상, 인덱스)으로 인덱스 연산을 수행합니다.
+	OpIndex
+	// OpReturnValue 는 스택 최상단 값을 반환값으로 하여 함수에서 돌아갑니다.
+	OpReturnValue
+	// OpReturn 은 반환값 없이 함수에서 돌아갑니다.
+	OpReturn
+	// OpCall 은 함수를 호출합니다. 피연산자(1바이트)는 인자의 개수입니다.
+	OpCall
+	// 지역 바인딩 명령어입니다. 피연산자(1바이트)는 지역 심볼의 인덱스입니다.
+	OpGetLocal
+	OpSetLocal
+	// OpGetBuiltin 은 내장 함수를 스택에 푸시합니다. 피연산자(1바이트)는 내장 함수 표의 인덱스로,
+	// object.Builtins 의 함수들 뒤에 호스트가 등록한 함수들이 이어집니다.
+	OpGetBuiltin
+	// OpCurrentClosure 는 현재 실행 중인 함수 자신을 스택에 푸시합니다. (재귀 호출용)
+	OpCurrentClosure
+	// OpClosure 는 상수 풀의 컴파일된 함수를 클로저로 감싸 스택에 푸시합니다.
+	// 첫 번째 피연산자(2바이트)는 상수 인덱스, 두 번째(1바이트)는 자유 변수의 개수입니다.
+	OpClosure
+	// OpConstantWide 는 OpConstant 와 같지만 4바이트 피연산자를 사용합니다.
+	// 상수 인덱스가 2바이트 범위(math.MaxUint16)를 넘을 때 사용됩니다.
+	OpConstantWide
+	// OpDup 은 스택 최상단 값을 다시 계산하지 않고 복제해 한 번 더 푸시합니다.
+	OpDup
+	// OpMod 는 스택의 두 값을 꺼내 나머지(%)를 구해 푸시합니다.
+	OpMod
+	// OpGreaterEqual 은 스택의 두 값을 꺼내 왼쪽이 오른쪽보다 크거나 같은지(>=) 비교합니다.
+	// a <= b 는 피연산자 순서를 바꿔 b >= a 로 컴파일됩니다.
+	OpGreaterEqual
+	// OpSetIndex 는 인덱스 대입(a[i] = v)을 수행합니다. 스택에는 아래에서부터
+	// 대상(배열/해시), 인덱스, 값 순서로 놓여 있어야 하며, 세 값을 모두 꺼낸 뒤 대입 표현식의 결과로
+	// 대입한 값을 다시 푸시합니다.
+	OpSetIndex
+	// OpConcatArray 는 스택 최상단의 배열 N개(피연산자, 1바이트)를 꺼내 순서대로 이어 붙인
+	// 새 배열 하나를 푸시합니다. 스프레드 배열 리터럴([...a, ...b])에 사용됩니다.
+	OpConcatArray
+	// OpGetLocal0, OpGetLocal1, OpGetLocal2 는 피연산자 없이 0~2번 지역 심볼을 푸시하는
+	// OpGetLocal 의 특수화된 형태입니다. 자주 쓰이는 앞쪽 슬롯에서 피연산자 읽기를 생략합니다.
+	OpGetLocal0
+	OpGetLocal1
+	OpGetLocal2
+
+	// opcodeCount 는 Opcode 목록의 끝을 표시하며, 그 값은 정의된 Opcode의 개수와 같습니다.
+	// 새 Opcode는 반드시 이 줄 위에 추가해야 합니다.
+	opcodeCount
 )
 
 // Definition 은 각 Opcode에 대한 명세입니다.
@@ -32,6 +106,56 @@ type Definition struct {
 var Definitions = map[Opcode]*Definition{
 	OpConstant: {"OpConstant", []int{2}},
 	OpAdd:      {"OpAdd", []int{}},
+	OpSub:      {"OpSub", []int{}},
+	OpMul:      {"OpMul", []int{}},
+	OpDiv:      {"OpDiv", []int{}},
+	OpPop:      {"OpPop", []int{}},
+	OpTrue:     {"OpTrue", []int{}},
+	OpFalse:    {"OpFalse", []int{}},
+
+	OpEqual:       {"OpEqual", []int{}},
+	OpNotEqual:    {"OpNotEqual", []int{}},
+	OpGreaterThan: {"OpGreaterThan", []int{}},
+
+	OpMinus: {"OpMinus", []int{}},
+	OpBang:  {"OpBang", []int{}},
+
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJump:          {"OpJump", []int{2}},
+
+	OpNull: {"OpNull", []int{}},
+
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetGlobal: {"OpSetGlobal", []int{2}},
+
+	OpArray: {"OpArray", []int{2}},
+	OpHash:  {"OpHash", []int{2}},
+	OpIndex: {"OpIndex", []int{}},
+
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+
+	OpCall: {"OpCall", []int{1}},
+
+	OpGetLocal: {"OpGetLocal", []int{1}},
+	OpSetLocal: {"OpSetLocal", []int{1}},
+
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpClosure:        {"OpClosure", []int{2, 1}},
+
+	OpConstantWide: {"OpConstantWide", []int{4}},
+
+	OpDup: {"OpDup", []int{}},
+	OpMod: {"OpMod", []int{}},
+
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpSetIndex:     {"OpSetIndex", []int{}},
+	OpConcatArray:  {"OpConcatArray", []int{1}},
+	OpGetLocal0:    {"OpGetLocal0", []int{}},
+	OpGetLocal1:    {"OpGetLocal1", []int{}},
+	OpGetLocal2:    {"OpGetLocal2", []int{}},
 }
 
 // Lookup 함수는 주어진 Opcode(바이트)에 해당하는 Definition을 찾습니다.
@@ -44,11 +168,73 @@ func Lookup(op byte) (*Definition, error) {
 	return def, nil
 }
 
+// InstructionLen 함수는 주어진 Opcode의 명령어가 차지하는 바이트 수
+// (Opcode 1바이트 + 모든 피연산자의 폭)를 반환합니다. 정의되지 않은 Opcode라면 에러를 반환합니다.
+func InstructionLen(op Opcode) (int, error) {
+	def, err := Lookup(byte(op))
+	if err != nil {
+		return 0, err
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+	return length, nil
+}
+
+// LookupByName 함수는 Definition 의 이름(예: "OpAdd")으로 Opcode를 찾습니다.
+// 만약 해당 이름의 Opcode가 없다면 에러를 반환합니다.
+func LookupByName(name string) (Opcode, error) {
+	for op, def := range Definitions {
+		if def.Name == name {
+			return op, nil
+		}
+	}
+	return 0, fmt.Errorf("opcode %q undefined", name)
+}
+
+// AllOpcodes 함수는 Definitions 에 등록된 모든 Opcode를 오름차순으로 반환합니다.
+func AllOpcodes() []Opcode {
+	ops := make([]Opcode, 0, len(Definitions))
+	for op := range Definitions {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	return ops
+}
+
 // Make 함수는 Opcode와 피연산자들을 이용해 바이트코드 Instruction을 생성합니다.
+// 정의되지 않은 Opcode이거나, 피연산자 개수가 맞지 않거나, 피연산자가 선언된 폭에 들어가지 않으면
+// 빈 슬라이스를 반환하므로, 입력을 신뢰할 수 없다면 MakeSafe 를 사용하세요.
 func Make(op Opcode, operands ...int) []byte {
+	instruction, err := MakeSafe(op, operands...)
+	if err != nil {
+		return []byte{}
+	}
+	return instruction
+}
+
+// MakeSafe 함수는 Make 와 같지만, 정의되지 않은 Opcode이거나 피연산자 개수가
+// Definition 과 맞지 않거나, 피연산자가 선언된 폭에 들어가지 않으면 에러를 반환합니다.
+func MakeSafe(op Opcode, operands ...int) ([]byte, error) {
 	def, ok := Definitions[op]
 	if !ok {
-		return []byte{}
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	if len(operands) != len(def.OperandWidths) {
+		return nil, fmt.Errorf("wrong number of operands for %s. want=%d, got=%d",
+			def.Name, len(def.OperandWidths), len(operands))
+	}
+
+	// 폭을 넘는 피연산자는 조용히 잘려서 다른 값이 되므로 미리 거부합니다.
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		// 32비트 플랫폼에서는 int 로 1<<32 를 계산하면 0 이 되므로 uint64 로 비교합니다.
+		if o < 0 || uint64(o) >= uint64(1)<<(8*width) {
+			return nil, fmt.Errorf("operand %d overflows %d-byte width", o, width)
+		}
 	}
 
 	// 명령어의 전체 길이를 계산합니다. (Opcode 1바이트 + 모든 피연산자의 길이)
@@ -69,10 +255,14 @@ func Make(op Opcode, operands ...int) []byte {
 		case 2:
 			// 2바이트 피연산자의 경우, Big-Endian 순서로 바이트를 씁니다.
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 4:
+			binary.BigEndian.PutUint32(instruction[offset:], uint32(o))
+		case 1:
+			instruction[offset] = byte(o)
 		}
 		offset += width
 	}
-	return instruction
+	return instruction, nil
 }
 
 func (ins Instructions) String() string {
@@ -82,11 +272,17 @@ func (ins Instructions) String() string {
 	for i < len(ins) {
 		def, err := Lookup(ins[i])
 		if err != nil {
+			// 알 수 없는 바이트는 건너뛰고 다음 바이트부터 계속 해석합니다.
 			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
 			continue
 		}
 
-		operands, read := ReadOperands(def, ins[i+1:])
+		operands, read, err := ReadOperandsSafe(def, ins[i+1:])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			break
+		}
 		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
 		i += 1 + read
 	}
@@ -94,6 +290,46 @@ func (ins Instructions) String() string {
 	return out.String()
 }
 
+// Iterate 메서드는 명령어 스트림을 처음부터 하나씩 해석하며, 각 명령어의 시작 오프셋과
+// Definition, 피연산자를 fn 에 넘겨 호출합니다. 정의되지 않은 Opcode를 만나거나
+// fn 이 에러를 반환하면 즉시 멈추고 그 에러를 반환합니다.
+func (ins Instructions) Iterate(fn func(offset int, def *Definition, operands []int) error) error {
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			return err
+		}
+
+		operands, read, err := ReadOperandsSafe(def, ins[i+1:])
+		if err != nil {
+			return err
+		}
+
+		err = fn(i, def, operands)
+		if err != nil {
+			return err
+		}
+
+		i += 1 + read
+	}
+
+	return nil
+}
+
+// OpcodeHistogram 메서드는 명령어 스트림에 각 Opcode가 몇 번 나타나는지 셉니다.
+// 정의되지 않은 Opcode를 만나면 그때까지 센 결과와 함께 에러를 반환합니다.
+func (ins Instructions) OpcodeHistogram() (map[Opcode]int, error) {
+	counts := make(map[Opcode]int)
+
+	err := ins.Iterate(func(offset int, def *Definition, operands []int) error {
+		counts[Opcode(ins[offset])]++
+		return nil
+	})
+
+	return counts, err
+}
+
 func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 	operandCount := len(def.OperandWidths)
 
@@ -107,6 +343,8 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
@@ -120,6 +358,10 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 		switch width {
 		case 2:
 			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
 		}
 
 		offset += width
@@ -128,6 +370,41 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 	return operands, offset
 }
 
+// ReadOperandsSafe 함수는 ReadOperands 와 같지만, Definition 에 지원하지 않는 피연산자 폭이
+// 있거나 명령어가 피연산자를 담기에 너무 짧으면 에러를 반환합니다.
+func ReadOperandsSafe(def *Definition, ins Instructions) ([]int, int, error) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		if len(ins) < offset+width {
+			return nil, 0, fmt.Errorf("instruction %s truncated: need %d bytes, have %d",
+				def.Name, offset+width, len(ins))
+		}
+
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
+		default:
+			return nil, 0, fmt.Errorf("unsupported operand width %d for %s", width, def.Name)
+		}
+
+		offset += width
+	}
+
+	return operands, offset, nil
+}
+
 func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
+
+func ReadUint8(ins Instructions) uint8 { return uint8(ins[0]) }
+
+func ReadUint32(ins Instructions) uint32 {
+	return binary.BigEndian.Uint32(ins)
+}
@@ -10,6 +10,10 @@ func TestMake(t *testing.T) {
 	}{
 		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpCall, []int{255}, []byte{byte(OpCall), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{OpConstantWide, []int{65536}, []byte{byte(OpConstantWide), 0, 1, 0, 0}},
+		{OpConstantWide, []int{2147483647}, []byte{byte(OpConstantWide), 127, 255, 255, 255}},
 	}
 
 	for _, tt := range tests {
@@ -28,16 +32,57 @@ func TestMake(t *testing.T) {
 	}
 }
 
+func TestMakeSafe(t *testing.T) {
+	instruction, err := MakeSafe(OpConstant, 65534)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []byte{byte(OpConstant), 255, 254}
+	if string(instruction) != string(expected) {
+		t.Errorf("instruction wrong. want=%v, got=%v", expected, instruction)
+	}
+}
+
+func TestMakeSafeErrors(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected string
+	}{
+		{Opcode(255), []int{}, "opcode 255 undefined"},
+		{OpConstant, []int{}, "wrong number of operands for OpConstant. want=1, got=0"},
+		{OpAdd, []int{1}, "wrong number of operands for OpAdd. want=0, got=1"},
+		{OpCall, []int{256}, "operand 256 overflows 1-byte width"},
+		{OpConstant, []int{65536}, "operand 65536 overflows 2-byte width"},
+		{OpClosure, []int{1, -1}, "operand -1 overflows 1-byte width"},
+	}
+
+	for _, tt := range tests {
+		_, err := MakeSafe(tt.op, tt.operands...)
+		if err == nil {
+			t.Errorf("expected error for %d %v, got none", tt.op, tt.operands)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
 func TestInstructionsString(t *testing.T) {
 	instructions := []Instructions{
 		Make(OpAdd),
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
+		Make(OpClosure, 65534, 255),
 	}
 
 	expected := `0000 OpAdd
 0001 OpConstant 2
 0004 OpConstant 65535
+0007 OpClosure 65534 255
 `
 
 	var concatted Instructions
@@ -58,6 +103,9 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{OpConstant, []int{65534}, 2},
+		{OpCall, []int{255}, 1},
+		{OpClosure, []int{65534, 255}, 3},
+		{OpConstantWide, []int{4294967295}, 4},
 	}
 
 	for _, tt := range tests {
@@ -80,3 +128,297 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestLookupByName(t *testing.T) {
+	op, err := LookupByName("OpAdd")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if op != OpAdd {
+		t.Errorf("opcode wrong. want=%d, got=%d", OpAdd, op)
+	}
+
+	_, err = LookupByName("OpNonexistent")
+	if err == nil {
+		t.Fatalf("expected error for unknown name, got none")
+	}
+	if err.Error() != `opcode "OpNonexistent" undefined` {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func TestAllOpcodes(t *testing.T) {
+	ops := AllOpcodes()
+
+	if len(ops) != len(Definitions) {
+		t.Fatalf("wrong number of opcodes. want=%d, got=%d", len(Definitions), len(ops))
+	}
+
+	seen := map[Opcode]bool{}
+	for _, op := range ops {
+		if seen[op] {
+			t.Errorf("opcode %d returned more than once", op)
+		}
+		seen[op] = true
+
+		if _, ok := Definitions[op]; !ok {
+			t.Errorf("opcode %d is not defined", op)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpConstant, 1),
+		Make(OpConstant, 2),
+		Make(OpAdd),
+		Make(OpCall, 3),
+	}
+
+	var concatted Instructions
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	type visit struct {
+		offset   int
+		name     string
+		operands []int
+	}
+
+	expected := []visit{
+		{0, "OpConstant", []int{1}},
+		{3, "OpConstant", []int{2}},
+		{6, "OpAdd", []int{}},
+		{7, "OpCall", []int{3}},
+	}
+
+	visited := []visit{}
+	err := concatted.Iterate(func(offset int, def *Definition, operands []int) error {
+		visited = append(visited, visit{offset, def.Name, operands})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(visited) != len(expected) {
+		t.Fatalf("wrong number of visits. want=%d, got=%d", len(expected), len(visited))
+	}
+
+	for i, want := range expected {
+		got := visited[i]
+		if got.offset != want.offset || got.name != want.name {
+			t.Errorf("visit %d wrong. want=%d %s, got=%d %s",
+				i, want.offset, want.name, got.offset, got.name)
+		}
+		if len(got.operands) != len(want.operands) {
+			t.Errorf("visit %d operands wrong. want=%v, got=%v", i, want.operands, got.operands)
+			continue
+		}
+		for j, o := range want.operands {
+			if got.operands[j] != o {
+				t.Errorf("visit %d operand %d wrong. want=%d, got=%d", i, j, o, got.operands[j])
+			}
+		}
+	}
+}
+
+func TestIterateStopsOnError(t *testing.T) {
+	concatted := append(Instructions{}, Make(OpAdd)...)
+	concatted = append(concatted, byte(255))
+	concatted = append(concatted, Make(OpAdd)...)
+
+	visits := 0
+	err := concatted.Iterate(func(offset int, def *Definition, operands []int) error {
+		visits++
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expected error for undefined opcode, got none")
+	}
+	if visits != 1 {
+		t.Errorf("wrong number of visits before error. want=%d, got=%d", 1, visits)
+	}
+}
+
+func TestReadOperandsSafe(t *testing.T) {
+	instruction := Make(OpClosure, 65534, 255)
+
+	def, err := Lookup(byte(OpClosure))
+	if err != nil {
+		t.Fatalf("definition not found: %q", err)
+	}
+
+	operands, n, err := ReadOperandsSafe(def, instruction[1:])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 3 {
+		t.Fatalf("n wrong. want=%d, got=%d", 3, n)
+	}
+	if operands[0] != 65534 || operands[1] != 255 {
+		t.Errorf("operands wrong. want=[65534 255], got=%v", operands)
+	}
+}
+
+func TestReadOperandsSafeErrors(t *testing.T) {
+	tests := []struct {
+		def      *Definition
+		ins      Instructions
+		expected string
+	}{
+		{
+			&Definition{"OpWeird", []int{3}},
+			Instructions{1, 2, 3},
+			"unsupported operand width 3 for OpWeird",
+		},
+		{
+			Definitions[OpConstant],
+			Instructions{1},
+			"instruction OpConstant truncated: need 2 bytes, have 1",
+		},
+	}
+
+	for _, tt := range tests {
+		_, _, err := ReadOperandsSafe(tt.def, tt.ins)
+		if err == nil {
+			t.Errorf("expected error for %s, got none", tt.def.Name)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestOpcodeHistogram(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpConstant, 0),
+		Make(OpConstant, 1),
+		Make(OpAdd),
+		Make(OpConstant, 2),
+		Make(OpAdd),
+		Make(OpPop),
+	}
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	counts, err := concatted.OpcodeHistogram()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[Opcode]int{
+		OpConstant: 3,
+		OpAdd:      2,
+		OpPop:      1,
+	}
+
+	if len(counts) != len(expected) {
+		t.Fatalf("wrong number of opcodes. want=%d, got=%d", len(expected), len(counts))
+	}
+
+	for op, want := range expected {
+		if counts[op] != want {
+			t.Errorf("wrong count for opcode %d. want=%d, got=%d", op, want, counts[op])
+		}
+	}
+}
+
+func TestOpcodeHistogramUndefinedOpcode(t *testing.T) {
+	ins := append(Make(OpPop), 255)
+
+	counts, err := Instructions(ins).OpcodeHistogram()
+	if err == nil {
+		t.Fatalf("expected error for undefined opcode, got none")
+	}
+
+	if counts[OpPop] != 1 {
+		t.Errorf("wrong count for OpPop. want=1, got=%d", counts[OpPop])
+	}
+}
+
+func TestInstructionLen(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		expected int
+	}{
+		{OpConstant, 3},
+		{OpAdd, 1},
+		{OpCall, 2},
+		{OpClosure, 4},
+		{OpConstantWide, 5},
+		{OpConcatArray, 2},
+		{OpGetLocal0, 1},
+	}
+
+	for _, tt := range tests {
+		length, err := InstructionLen(tt.op)
+		if err != nil {
+			t.Fatalf("unexpected error for opcode %d: %s", tt.op, err)
+		}
+
+		if length != tt.expected {
+			t.Errorf("wrong length for opcode %d. want=%d, got=%d", tt.op, tt.expected, length)
+		}
+	}
+
+	_, err := InstructionLen(Opcode(255))
+	if err == nil {
+		t.Fatalf("expected error for undefined opcode, got none")
+	}
+}
+
+func TestOpDup(t *testing.T) {
+	ins := Instructions(Make(OpDup))
+	if len(ins) != 1 {
+		t.Fatalf("OpDup has wrong length. want=1, got=%d", len(ins))
+	}
+
+	expected := "0000 OpDup\n"
+	if ins.String() != expected {
+		t.Errorf("OpDup disassembled wrongly.\nwant=%q\ngot =%q", expected, ins.String())
+	}
+}
+
+func TestInstructionsStringMalformed(t *testing.T) {
+	ins := Instructions{255}
+	ins = append(ins, Make(OpPop)...)
+	ins = append(ins, byte(OpConstant), 0)
+
+	expected := `ERROR: opcode 255 undefined
+0001 OpPop
+ERROR: instruction OpConstant truncated: need 2 bytes, have 1
+`
+
+	if ins.String() != expected {
+		t.Errorf("malformed instructions wrongly formatted.\nwant=%q\ngot =%q",
+			expected, ins.String())
+	}
+}
+
+// TestAllOpcodesDefined는 OpConstant 부터 마지막 Opcode까지 모든 Opcode가 Definitions 에
+// 등록되어 있는지 확인합니다. 등록이 빠지면 Make 가 조용히 빈 슬라이스를 반환하기 때문입니다.
+func TestAllOpcodesDefined(t *testing.T) {
+	for op := OpConstant; op < opcodeCount; op++ {
+		def, ok := Definitions[op]
+		if !ok || def == nil {
+			t.Errorf("opcode %d has no definition", op)
+			continue
+		}
+
+		if def.Name == "" {
+			t.Errorf("opcode %d has an empty name", op)
+		}
+	}
+
+	if len(Definitions) != int(opcodeCount) {
+		t.Errorf("Definitions has entries outside the opcode list. want=%d, got=%d",
+			opcodeCount, len(Definitions))
+	}
+}
@@ -2,31 +2,263 @@ package compiler
 
 import (
 	"fmt"
+	"math"
 	"monkey/ast"
 	"monkey/code"
 	"monkey/object"
+	"sort"
+	"strconv"
 )
 
+// DefaultMaxGlobals 는 New 가 설정하는 MaxGlobals 의 기본값으로, OpSetGlobal 의 2바이트
+// 피연산자로 표현할 수 있는 인덱스의 개수와 같습니다.
+const DefaultMaxGlobals = 65536
+
+// maxLocals 는 함수 하나가 가질 수 있는 지역 바인딩(매개변수 포함)의 개수로,
+// OpGetLocal/OpSetLocal 의 1바이트 피연산자로 표현할 수 있는 인덱스의 개수와 같습니다.
+const maxLocals = 256
+
 type Compiler struct {
-	instructions code.Instructions // 컴파일된 바이트코드 명령어
-	constants    []object.Object   // 상수 풀
+	constants []object.Object // 상수 풀
+
+	symbolTable *SymbolTable // 식별자 이름을 심볼로 해석하는 심볼 테이블
+
+	scopes     []CompilationScope // 컴파일 스코프 스택 (0번은 최상위 프로그램)
+	scopeIndex int                // 현재 컴파일 중인 스코프의 인덱스
+
+	// DeduplicateConstants 가 true 이면, 같은 타입과 같은 값(Inspect)을 가진 상수는
+	// 상수 풀에 새로 추가하지 않고 기존 인덱스를 재사용합니다.
+	DeduplicateConstants bool
+
+	// Optimize 가 true 이면, 컴파일된 명령어 스트림에 핍홀 최적화(optimize)를 적용합니다.
+	Optimize bool
+
+	// StrictDivision 이 true 이면, 나누는 수가 정수 리터럴 0 인 나눗셈을 실행 시점까지 미루지 않고
+	// 컴파일 에러로 만듭니다. 나누는 수가 리터럴이 아니면 값을 알 수 없으므로 검사하지 않습니다.
+	StrictDivision bool
+
+	// KeepLastValue 가 true 이면, 최상위 프로그램의 마지막 문장이 표현식문일 때 그 OpPop 을
+	// 내보내지 않아 값이 스택에 남습니다. REPL 이 마지막 값을 출력할 때 사용합니다.
+	KeepLastValue bool
+
+	// previousConstants 는 NewIncremental 로 만든 컴파일러에서 이전 상수 풀의 값을
+	// 인덱스로 찾기 위한 맵입니다. 키는 constantKey 로 만듭니다.
+	previousConstants map[string]int
+
+	// MaxGlobals 는 전역 바인딩 인덱스의 상한입니다. VM 은 이 크기의 고정 배열에 전역 값을
+	// 저장하므로, 이 값 이상의 인덱스를 가진 전역 바인딩은 컴파일 에러가 됩니다. 기본값은 65536 입니다.
+	MaxGlobals int
+
+	// TraceFunc 가 설정되어 있으면, emit 이 명령어를 내보낼 때마다 Opcode, 피연산자,
+	// 현재 스코프에서의 위치와 함께 호출됩니다. 내보내는 바이트에는 영향을 주지 않습니다.
+	// 호출은 나중에 고쳐지기 전의 스트림을 보여 줍니다. 점프는 임시 피연산자(9999)로 보고되고,
+	// 나중에 removeLastPop 으로 지워지거나 OpReturnValue 로 바뀌는 OpPop 도 보고되며,
+	// Optimize 가 적용한 변경은 보고되지 않습니다. 최종 결과는 Bytecode 로 확인해야 합니다.
+	TraceFunc func(op code.Opcode, operands []int, pos int)
+
+	// err 는 emit 이나 changeOperand 가 명령어를 인코딩하지 못했을 때 기록되는 첫 번째 에러입니다.
+	// Compile 은 노드를 컴파일할 때마다 이 값을 확인하며, Reset 을 호출하기 전까지 계속 반환합니다.
+	err error
+}
+
+// CompilationScope 는 함수 본문처럼 독립된 명령어 스트림을 가지는 컴파일 단위입니다.
+type CompilationScope struct {
+	instructions        code.Instructions  // 이 스코프에서 컴파일된 바이트코드 명령어
+	lastInstruction     EmittedInstruction // 마지막으로 내보낸 명령어
+	previousInstruction EmittedInstruction // 마지막 바로 이전에 내보낸 명령어
+}
+
+// EmittedInstruction 은 내보낸 명령어의 Opcode와 명령어 스트림 내 위치를 기록합니다.
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
 }
 
 func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
 	return &Compiler{
-		instructions: code.Instructions{},
-		constants:    []object.Object{},
+		constants:   []object.Object{},
+		symbolTable: newGlobalSymbolTable(),
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+		MaxGlobals:  DefaultMaxGlobals,
+	}
+}
+
+// NewIncremental 함수는 이전 컴파일 결과(prev)의 상수 인덱스를 유지하는 컴파일러를 만듭니다.
+// 새 상수 풀은 prev 의 상수 풀로 시작하고, 값이 같은 상수는 이전 인덱스를 재사용하며
+// 새로운 값만 끝에 덧붙입니다. 편집기처럼 조금씩 바뀐 소스를 반복해서 컴파일할 때,
+// 바뀌지 않은 상수의 인덱스가 그대로 유지됩니다.
+// 컴파일된 함수는 값으로 비교할 수 없으므로 매번 새로 덧붙여집니다.
+func NewIncremental(prev *Bytecode) *Compiler {
+	compiler := New()
+	compiler.constants = append([]object.Object{}, prev.Constants...)
+	compiler.previousConstants = map[string]int{}
+
+	for i, c := range prev.Constants {
+		key, ok := constantKey(c)
+		if !ok {
+			continue
+		}
+		if _, exists := compiler.previousConstants[key]; !exists {
+			compiler.previousConstants[key] = i
+		}
+	}
+
+	return compiler
+}
+
+// constantKey 함수는 값으로 비교할 수 있는 상수에 대해 타입과 값을 합친 키를 만듭니다.
+func constantKey(obj object.Object) (string, bool) {
+	switch obj.(type) {
+	case *object.Integer, *object.String:
+		return string(obj.Type()) + ":" + obj.Inspect(), true
+	}
+	return "", false
+}
+
+// newGlobalSymbolTable 함수는 내장 함수들이 미리 정의된 최상위 심볼 테이블을 만듭니다.
+func newGlobalSymbolTable() *SymbolTable {
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	return symbolTable
+}
+
+// NewWithState 함수는 기존의 심볼 테이블과 상수 풀을 이어받는 컴파일러를 만듭니다.
+// REPL 처럼 여러 번의 Compile 호출 사이에 전역 바인딩과 상수를 유지해야 할 때 사용합니다.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+// Reset 메서드는 컴파일러를 다시 사용할 수 있도록 스코프를 하나의 빈 최상위 스코프로 되돌립니다.
+// keepState 가 true 이면 상수 풀과 전역 심볼 테이블을 유지하고, false 이면 New 직후의 상태로 비웁니다.
+// 조각을 반복해서 컴파일할 때 할당을 줄이도록 최상위 명령어 슬라이스만 재사용합니다. Bytecode 는 명령어와
+// 상수를 복사해 반환하므로, Reset 이전에 받은 Bytecode 와 NewWithState 에 넘긴 상수 풀, 심볼 테이블은
+// 그대로 유효합니다.
+func (c *Compiler) Reset(keepState bool) {
+	c.scopes = c.scopes[:1]
+	c.scopes[0] = CompilationScope{
+		instructions:        c.scopes[0].instructions[:0],
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopeIndex = 0
+	c.err = nil
+
+	if keepState {
+		return
+	}
+
+	c.constants = []object.Object{}
+	c.symbolTable = newGlobalSymbolTable()
+	c.previousConstants = nil
+}
+
+// RegisterBuiltin 메서드는 호스트 프로그램의 Go 함수를 이 컴파일러의 내장 함수로 등록합니다.
+// 함수는 패키지 전역인 object.Builtins 가 아니라 전역 심볼 테이블에 기록되므로, 다른 컴파일러에는
+// 영향을 주지 않습니다. 이후 name 을 참조하면 OpGetBuiltin 이 내보내지며, VM 은 Bytecode.Builtins 에서
+// 같은 인덱스로 함수를 찾습니다. NewWithState 로 심볼 테이블을 넘기면 등록한 함수도 함께 넘어가고,
+// Reset(false) 는 전역 심볼 테이블을 새로 만들므로 등록도 사라집니다.
+// 이미 있는 내장 함수 이름이거나 인덱스가 OpGetBuiltin 의 1바이트 피연산자를 넘으면 에러를 반환합니다.
+func (c *Compiler) RegisterBuiltin(name string, fn *object.Builtin) error {
+	global := c.globalSymbolTable()
+
+	if existing, ok := global.store[name]; ok && existing.Scope == BuiltinScope {
+		return fmt.Errorf("builtin %s is already defined", name)
+	}
+
+	index := len(object.Builtins) + len(global.hostBuiltins)
+	if index > math.MaxUint8 {
+		return fmt.Errorf("too many builtins (max %d)", math.MaxUint8+1)
+	}
+
+	global.hostBuiltins = append(global.hostBuiltins, fn)
+	global.DefineBuiltin(index, name)
+	return nil
+}
+
+// globalSymbolTable 메서드는 현재 심볼 테이블에서 바깥으로 거슬러 올라간 최상위 전역 테이블을 반환합니다.
+func (c *Compiler) globalSymbolTable() *SymbolTable {
+	global := c.symbolTable
+	for global.Outer != nil {
+		global = global.Outer
 	}
+	return global
 }
 
+// builtinTable 함수는 OpGetBuiltin 의 피연산자로 찾을 내장 함수 표를 만듭니다.
+// object.Builtins 의 함수들 뒤에 호스트가 등록한 함수(host)들이 이어집니다.
+func builtinTable(host []*object.Builtin) []*object.Builtin {
+	table := make([]*object.Builtin, 0, len(object.Builtins)+len(host))
+	for _, b := range object.Builtins {
+		table = append(table, b.Builtin)
+	}
+	return append(table, host...)
+}
+
+// addConstant 메서드는 객체를 상수 풀에 추가하고, 해당 상수의 인덱스를 반환합니다.
+// 이 인덱스는 OpConstant 명령어의 피연산자로 사용됩니다.
 func (c *Compiler) addConstant(obj object.Object) int {
+	if c.previousConstants != nil {
+		if key, ok := constantKey(obj); ok {
+			if i, ok := c.previousConstants[key]; ok {
+				return i
+			}
+		}
+	}
+
+	if c.DeduplicateConstants {
+		for i, existing := range c.constants {
+			if existing.Type() == obj.Type() && existing.Inspect() == obj.Inspect() {
+				return i
+			}
+		}
+	}
+
 	c.constants = append(c.constants, obj)
 	return len(c.constants) - 1
 }
 
-// Compile 메서드는 주어진 AST 노드를 컴파일합니다.
-// 현재는 아무 작업도 하지 않고 nil을 반환합니다.
+// SymbolTable 메서드는 컴파일러의 현재 심볼 테이블을 반환합니다.
+// REPL 은 이 테이블을 보관했다가 NewWithState 에 다시 넘겨 전역 바인딩을 이어 갈 수 있습니다.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+// AddConstant 메서드는 호스트가 컴파일 전에 상수를 미리 넣어 둘 수 있도록 addConstant 를 공개합니다.
+// 반환된 인덱스는 직접 만든 OpConstant 명령어의 피연산자로 사용할 수 있습니다.
+func (c *Compiler) AddConstant(obj object.Object) int {
+	return c.addConstant(obj)
+}
+
+// Constants 메서드는 현재까지의 상수 풀을 반환합니다.
+func (c *Compiler) Constants() []object.Object {
+	return c.constants
+}
+
+// Compile 메서드는 주어진 AST 노드를 재귀적으로 순회하며 바이트코드로 컴파일합니다.
 func (c *Compiler) Compile(node ast.Node) error {
+	err := c.compileNode(node)
+	if err != nil {
+		return err
+	}
+
+	// 피연산자가 폭을 넘는 등 인코딩하지 못한 명령어가 있으면, 그 명령어가 빠진 스트림 대신 에러를 반환합니다.
+	return c.err
+}
+
+// compileNode 메서드는 노드의 종류에 따라 명령어를 내보냅니다. 하위 노드는 Compile 로 재귀합니다.
+func (c *Compiler) compileNode(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
 		for _, s := range node.Statements {
@@ -36,13 +268,51 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
+		// 마지막 표현식문의 값을 스택에 남겨 두도록 그 OpPop 만 제거합니다.
+		if c.KeepLastValue && len(node.Statements) > 0 {
+			_, isExpression := node.Statements[len(node.Statements)-1].(*ast.ExpressionStatement)
+			if isExpression && c.lastInstructionIs(code.OpPop) {
+				c.removeLastPop()
+			}
+		}
+
+		if c.Optimize {
+			c.optimizeCurrentScope()
+		}
+
 	case *ast.ExpressionStatement:
 		err := c.Compile(node.Expression)
 		if err != nil {
 			return err
 		}
+		// 표현식문의 결과는 사용되지 않으므로 스택에서 꺼내 스택을 비워 둡니다.
+		c.emit(code.OpPop)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+
+		// a < b 는 피연산자 순서를 바꿔 b > a 로, a <= b 는 b >= a 로 컴파일합니다.
+		if node.Operator == "<" || node.Operator == "<=" {
+			err := c.Compile(node.Right)
+			if err != nil {
+				return err
+			}
+
+			err = c.Compile(node.Left)
+			if err != nil {
+				return err
+			}
+
+			if node.Operator == "<" {
+				c.emit(code.OpGreaterThan)
+			} else {
+				c.emit(code.OpGreaterEqual)
+			}
+			return nil
+		}
+
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -56,40 +326,857 @@ func (c *Compiler) Compile(node ast.Node) error {
 		switch node.Operator {
 		case "+":
 			c.emit(code.OpAdd)
+		case "-":
+			c.emit(code.OpSub)
+		case "*":
+			c.emit(code.OpMul)
+		case "/":
+			// 엄격 모드에서는 리터럴 0 으로 나누는 것을 실행 전에 거부합니다.
+			if divisor, ok := node.Right.(*ast.IntegerLiteral); ok && c.StrictDivision {
+				if divisor.Value == 0 {
+					return fmt.Errorf("division by zero")
+				}
+			}
+			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case ">":
+			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterEqual)
+		case "==":
+			c.emit(code.OpEqual)
+		case "!=":
+			c.emit(code.OpNotEqual)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.PrefixExpression:
+		err := c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		case "+":
+			// 단항 + 는 항등 연산이므로 피연산자만 남깁니다.
 		default:
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
 
+	case *ast.IfExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+
+		// 점프 위치는 아직 알 수 없으므로 임시 값(9999)을 넣고 나중에 수정합니다.
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		err = c.Compile(node.Consequence)
+		if err != nil {
+			return err
+		}
+
+		// if 표현식은 값을 남겨야 하므로 결과 블록 마지막의 OpPop 을 제거합니다.
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		}
+
+		// 결과 블록을 실행한 뒤에는 대안 블록을 건너뜁니다.
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		// 조건이 거짓이면 대안 블록의 시작 위치로 점프하도록 수정합니다.
+		afterConsequencePos := c.Position()
+		c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+
+		// else 가 없더라도 if 표현식이 값을 남기도록 OpNull 을 내보냅니다.
+		if node.Alternative == nil {
+			c.emit(code.OpNull)
+		} else {
+			err := c.Compile(node.Alternative)
+			if err != nil {
+				return err
+			}
+
+			if c.lastInstructionIs(code.OpPop) {
+				c.removeLastPop()
+			}
+		}
+
+		afterAlternativePos := c.Position()
+		c.changeOperand(jumpPos, afterAlternativePos)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+
+			// 블록 최상위의 return 뒤에 오는 문장은 실행될 수 없으므로 컴파일하지 않습니다.
+			// (if 분기 안의 return 처럼 조건부로 반환하는 경우는 ExpressionStatement 이므로 해당하지 않습니다.)
+			if _, ok := s.(*ast.ReturnStatement); ok {
+				break
+			}
+		}
+
+	case *ast.LetStatement:
+		// 함수 본문은 바인딩이 끝난 뒤에 실행되므로, 재귀 호출을 위해 심볼을 먼저 정의합니다.
+		// 그 밖의 값은 정의보다 먼저 컴파일해서, let x = x; 처럼 아직 값이 없는 바인딩을 읽지 못하게 합니다.
+		fn, isFunction := node.Value.(*ast.FunctionLiteral)
+		if !isFunction {
+			err := c.Compile(node.Value)
+			if err != nil {
+				return err
+			}
+		}
+
+		symbol, err := c.defineBinding(node.Name.Value)
+		if err != nil {
+			return err
+		}
+
+		if isFunction {
+			// 전역 함수는 자기 자신을 OpGetGlobal 로 참조합니다. 지역 함수는 바깥 함수의 지역
+			// 바인딩을 읽을 수 없으므로, 본문 안에서 자기 이름을 OpCurrentClosure 로 해석하게 합니다.
+			name := node.Name.Value
+			if symbol.Scope == GlobalScope {
+				name = ""
+			}
+			err = c.compileFunctionLiteral(fn, name)
+			if err != nil {
+				return err
+			}
+		}
+
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Value)
+		}
+
+		err := c.checkCapture(symbol)
+		if err != nil {
+			return err
+		}
+
+		c.loadSymbol(symbol)
+
 	case *ast.IntegerLiteral:
+		// 파서가 int64 범위를 넘는 리터럴을 넘기면, 잘린 값을 조용히 저장하지 않고 에러를 냅니다.
+		// 직접 만든 AST 처럼 토큰 리터럴이 비어 있으면 Value 를 그대로 믿습니다.
+		if node.Token.Literal != "" {
+			if _, err := strconv.ParseInt(node.Token.Literal, 0, 64); err != nil {
+				return fmt.Errorf("integer literal out of range: %s", node.TokenLiteral())
+			}
+		}
+
 		integer := &object.Integer{Value: node.Value}
-		c.emit(code.OpConstant, c.addConstant(integer))
+		c.emitConstant(c.addConstant(integer))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emitConstant(c.addConstant(str))
+
+	case *ast.ArrayLiteral:
+		// OpArray 의 원소 개수 피연산자는 2바이트입니다.
+		if len(node.Elements) > math.MaxUint16 {
+			return fmt.Errorf("too many elements in array literal (max %d)", math.MaxUint16)
+		}
+
+		// 원소를 왼쪽부터 차례로 스택에 푸시한 뒤 개수와 함께 OpArray 를 내보냅니다.
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		// OpHash 의 피연산자는 키와 값을 합친 개수(2바이트)입니다.
+		if len(node.Pairs)*2 > math.MaxUint16 {
+			return fmt.Errorf("too many pairs in hash literal (max %d)", math.MaxUint16/2)
+		}
+
+		keys := []ast.Expression{}
+		for k, v := range node.Pairs {
+			// 키나 값 중 하나가 빠진 쌍은 스택에 홀수 개의 원소를 남겨 OpHash 피연산자와 어긋납니다.
+			if k == nil || v == nil {
+				return fmt.Errorf("malformed hash literal")
+			}
+			keys = append(keys, k)
+		}
+		// Go 의 map 순회 순서는 일정하지 않으므로, 출력이 결정적이도록 키를 정렬합니다.
+		sortHashKeys(keys, node.Pairs)
+
+		for _, k := range keys {
+			err := c.Compile(k)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(node.Pairs[k])
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+
+	case *ast.FunctionLiteral:
+		err := c.compileFunctionLiteral(node, "")
+		if err != nil {
+			return err
+		}
+
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpReturnValue)
+
+	case *ast.CallExpression:
+		// OpCall 의 인자 개수 피연산자는 1바이트입니다.
+		if len(node.Arguments) > math.MaxUint8 {
+			return fmt.Errorf("too many arguments in call (max %d)", math.MaxUint8)
+		}
+
+		// 호출할 함수를 먼저 스택에 올리고, 그 위에 인자들을 차례로 푸시합니다.
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	default:
+		// 처리하지 않는 노드를 조용히 건너뛰면 잘못된 바이트코드가 만들어지므로 에러로 알립니다.
+		return fmt.Errorf("unknown AST node type: %T", node)
+	}
+
+	return nil
+}
+
+// defineBinding 메서드는 let 바인딩의 심볼을 현재 심볼 테이블에 정의합니다.
+// 다음 인덱스가 전역 또는 지역 슬롯의 상한을 넘으면 심볼을 정의하기 전에 에러를 반환하므로,
+// 거부된 let 이 REPL 처럼 재사용되는 심볼 테이블의 슬롯을 차지하지 않습니다.
+func (c *Compiler) defineBinding(name string) (Symbol, error) {
+	next := c.symbolTable.numDefinitions
+	if c.symbolTable.Outer == nil && next >= c.MaxGlobals {
+		return Symbol{}, fmt.Errorf("too many global bindings (max %d)", c.MaxGlobals)
+	}
+	if c.symbolTable.Outer != nil && next >= maxLocals {
+		return Symbol{}, fmt.Errorf("too many local bindings (max %d)", maxLocals)
+	}
+
+	return c.symbolTable.DefineUnique(name)
+}
+
+// CompileStatement 메서드는 최상위 문장 하나를 현재 스코프에 이어서 컴파일합니다.
+// 파서가 문장을 하나씩 만들어 내는 대로 넘기면, 전체 *ast.Program 을 만들지 않고도
+// 여러 문장을 하나의 명령어 스트림으로 모을 수 있습니다.
+// Optimize 는 *ast.Program 을 컴파일할 때만 적용되므로, 이 경로에서는 적용되지 않습니다.
+func (c *Compiler) CompileStatement(stmt ast.Statement) error {
+	return c.Compile(stmt)
+}
+
+// CompileExpression 메서드는 표현식 하나를 문장으로 감싸지 않고 컴파일합니다.
+// 표현식문과 달리 OpPop 을 내보내지 않으므로 결과 값이 스택에 남습니다.
+// 표현식 하나만 평가하는 경우에는 KeepLastValue 보다 이 메서드가 간단합니다.
+func (c *Compiler) CompileExpression(expr ast.Expression) error {
+	return c.Compile(expr)
+}
+
+// CompileThunk 메서드는 표현식 하나를 인자 없는 CompiledFunction 으로 감싸 컴파일합니다.
+// 표현식은 새 스코프에서 컴파일되고 그 값이 OpReturnValue 로 반환되므로,
+// 호스트는 결과 함수를 다른 Monkey 함수처럼 호출할 수 있습니다.
+// 함수는 상수 풀에 추가되지 않으며, 본문이 참조하는 상수는 이 컴파일러의 상수 풀에 남습니다.
+func (c *Compiler) CompileThunk(expr ast.Expression) (*object.CompiledFunction, error) {
+	c.enterScope()
+
+	err := c.Compile(expr)
+	if err != nil {
+		// 에러가 나도 컴파일러를 계속 쓸 수 있도록 스코프를 되돌립니다.
+		c.leaveScope()
+		return nil, err
+	}
+
+	c.emit(code.OpReturnValue)
+	if c.err != nil {
+		c.leaveScope()
+		return nil, c.err
+	}
+
+	numLocals := c.symbolTable.numDefinitions
+	if c.Optimize {
+		c.optimizeCurrentScope()
+	}
+	instructions := c.leaveScope()
+
+	return &object.CompiledFunction{
+		Instructions: instructions,
+		NumLocals:    numLocals,
+	}, nil
+}
+
+// compileFunctionLiteral 메서드는 함수 리터럴을 새 스코프에서 컴파일해 CompiledFunction 상수로 만듭니다.
+// name 이 비어 있지 않으면, 함수 본문에서 그 이름이 자기 자신(OpCurrentClosure)으로 해석됩니다.
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral, name string) error {
+	c.enterScopeWithHint(len(node.Body.Statements) * instructionBytesPerStatement)
+
+	if name != "" {
+		c.symbolTable.DefineFunctionName(name)
+	}
+
+	if len(node.Parameters) > maxLocals {
+		c.leaveScope()
+		return fmt.Errorf("too many local bindings (max %d)", maxLocals)
+	}
+
+	// 매개변수는 본문보다 먼저 정의되어 지역 슬롯 0..n-1 을 차지합니다.
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	err := c.Compile(node.Body)
+	if err != nil {
+		// 에러가 나도 컴파일러를 계속 쓸 수 있도록 바깥 스코프로 되돌아갑니다.
+		c.leaveScope()
+		return err
+	}
+
+	// 마지막 표현식의 값을 암묵적으로 반환합니다.
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	// 반환할 값이 없는 본문(빈 함수, let 문으로 끝나는 함수 등)은 OpReturn 으로 끝냅니다.
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	// 스코프를 벗어나기 전에, 이 함수의 심볼 테이블에 정의된 지역 바인딩 수를 기록합니다.
+	numLocals := c.symbolTable.numDefinitions
+	if c.Optimize {
+		c.optimizeCurrentScope()
+	}
+	instructions := c.leaveScope()
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
 	}
+	c.emitConstant(c.addConstant(compiledFn))
 
 	return nil
 }
 
+// compileLogicalExpression 메서드는 && 와 || 를 단락 평가(short-circuit)로 컴파일합니다.
+// 왼쪽 피연산자만으로 결과가 정해지면 오른쪽 피연산자는 평가하지 않고 왼쪽 값이 그대로 결과가 됩니다.
+// OpJumpNotTruthy 가 조건 값을 꺼내 버리므로, 왼쪽 값을 OpDup 으로 복제해 두었다가
+// 오른쪽을 평가하는 경로에서만 OpPop 으로 버립니다.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpDup)
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if node.Operator == "&&" {
+		// 왼쪽이 참이면 왼쪽 값을 버리고 오른쪽 값이 결과가 됩니다.
+		c.emit(code.OpPop)
+		err = c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		// 왼쪽이 거짓이면 거짓인 왼쪽 값이 남은 채로 끝으로 점프합니다.
+		c.changeOperand(jumpNotTruthyPos, c.Position())
+		return nil
+	}
+
+	// 왼쪽이 참이면 오른쪽을 건너뛰고 왼쪽 값이 결과가 됩니다.
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	// 왼쪽이 거짓이면 왼쪽 값을 버리고 오른쪽 값이 결과가 됩니다.
+	c.changeOperand(jumpNotTruthyPos, c.Position())
+	c.emit(code.OpPop)
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, c.Position())
+	return nil
+}
+
+// sortHashKeys 함수는 해시 리터럴의 키를 정렬합니다. 모든 키가 정수 리터럴이면 값의 크기 순으로,
+// 그렇지 않으면 String() 의 사전 순으로 정렬합니다. 사전 순으로는 "10" 이 "2" 보다 앞서기 때문입니다.
+// {1: "a", 1: "b"} 처럼 같은 키가 여러 번 나오면 값(pairs)의 String() 순으로 정렬해 출력을 결정적으로 만듭니다.
+func sortHashKeys(keys []ast.Expression, pairs map[ast.Expression]ast.Expression) {
+	integers := true
+	for _, k := range keys {
+		if _, ok := k.(*ast.IntegerLiteral); !ok {
+			integers = false
+			break
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if integers {
+			a, b := keys[i].(*ast.IntegerLiteral).Value, keys[j].(*ast.IntegerLiteral).Value
+			if a != b {
+				return a < b
+			}
+		} else if a, b := keys[i].String(), keys[j].String(); a != b {
+			return a < b
+		}
+
+		return pairs[keys[i]].String() < pairs[keys[j]].String()
+	})
+}
+
 // Bytecode 메서드는 컴파일된 바이트코드 명령어와 상수 풀을 포함하는 Bytecode 객체를 반환합니다.
 // 이렇게 만들어진 Bytecode 객체는 가상 머신에서 실행할 수 있습니다.
+// 명령어와 상수 풀은 복사되므로, 이후의 Compile 이나 Reset 이 반환된 Bytecode 를 바꾸지 않습니다.
 func (c *Compiler) Bytecode() *Bytecode {
-	return &Bytecode{
-		Instructions: c.instructions,
-		Constants:    c.constants,
-	}
+	builtins := builtinTable(c.globalSymbolTable().hostBuiltins)
+	instructions := append(code.Instructions{}, c.currentInstructions()...)
+	constants := append([]object.Object{}, c.constants...)
+	return newBytecode(instructions, constants, builtins)
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+
+	// MaxStackDepth 는 최상위 명령어를 실행하는 동안 스택이 도달하는 최대 깊이입니다.
+	// 분기가 있으면 모든 경로 중 가장 깊은 값을 취합니다. 함수 호출로 쌓이는 깊이는 포함하지 않으며,
+	// 스택 균형이 맞지 않아 계산할 수 없으면 0 입니다(원인은 Verify 로 확인할 수 있습니다).
+	MaxStackDepth int
+
+	// Builtins 는 OpGetBuiltin 의 피연산자가 가리키는 내장 함수 표입니다. object.Builtins 의 함수들 뒤에
+	// RegisterBuiltin 으로 등록한 호스트 함수들이 이어집니다. Go 함수는 직렬화할 수 없으므로
+	// DeserializeBytecode 로 읽은 바이트코드에는 object.Builtins 의 함수만 들어 있습니다.
+	Builtins []*object.Builtin
+}
+
+// WalkConstants 메서드는 상수 풀의 각 상수에 대해 인덱스와 함께 fn 을 호출합니다.
+// 컴파일된 함수는 자신만의 상수 풀을 갖지 않고 프로그램 전체가 하나의 상수 풀을 공유하므로,
+// 함수 본문이 참조하는 상수도 이 평평한(flat) 풀에 들어 있어 따로 재귀할 필요가 없습니다.
+func (b *Bytecode) WalkConstants(fn func(index int, obj object.Object)) {
+	for i, c := range b.Constants {
+		fn(i, c)
+	}
+}
+
+// CoveredOpcodes 메서드는 최상위 명령어와 상수 풀의 모든 컴파일된 함수에 나타나는 Opcode의 집합을 반환합니다.
+// 테스트 도구가 여러 케이스의 결과를 합쳐, 어떤 Opcode가 컴파일 결과에서 검증되었는지 확인할 때 사용합니다.
+// 해석할 수 없는 명령어를 만나면 그 스트림은 그때까지 읽은 Opcode만 포함합니다.
+func (b *Bytecode) CoveredOpcodes() map[code.Opcode]bool {
+	covered := map[code.Opcode]bool{}
+
+	add := func(ins code.Instructions) {
+		counts, _ := ins.OpcodeHistogram()
+		for op := range counts {
+			covered[op] = true
+		}
+	}
+
+	add(b.Instructions)
+	b.WalkConstants(func(index int, obj object.Object) {
+		if fn, ok := obj.(*object.CompiledFunction); ok {
+			add(fn.Instructions)
+		}
+	})
+
+	return covered
+}
+
+// GlobalCount 메서드는 프로그램이 사용하는 전역 바인딩 슬롯의 수를 반환합니다.
+// 최상위 명령어와 컴파일된 함수 상수에서 가장 큰 OpSetGlobal/OpGetGlobal 피연산자에 1을 더한 값이며,
+// 전역을 전혀 쓰지 않으면 0 입니다. VM 은 고정 크기 대신 이 값으로 전역 배열을 할당할 수 있습니다.
+func (b *Bytecode) GlobalCount() int {
+	count := 0
+
+	scan := func(ins code.Instructions) {
+		ins.Iterate(func(offset int, def *code.Definition, operands []int) error {
+			switch code.Opcode(ins[offset]) {
+			case code.OpSetGlobal, code.OpGetGlobal:
+				if operands[0]+1 > count {
+					count = operands[0] + 1
+				}
+			}
+			return nil
+		})
+	}
+
+	scan(b.Instructions)
+	b.WalkConstants(func(index int, obj object.Object) {
+		if fn, ok := obj.(*object.CompiledFunction); ok {
+			scan(fn.Instructions)
+		}
+	})
+
+	return count
+}
+
+// Clone 메서드는 바이트코드의 깊은 복사본을 반환합니다. 명령어 바이트와 상수 풀을 새로 만들고,
+// 배열, 해시, 컴파일된 함수처럼 변경될 수 있는 상수도 복사하므로, 여러 VM 이 같은 바이트코드를
+// 각자의 복사본으로 안전하게 실행할 수 있습니다. 정수와 문자열처럼 변경되지 않는 상수는 공유합니다.
+func (b *Bytecode) Clone() *Bytecode {
+	constants := make([]object.Object, len(b.Constants))
+	for i, c := range b.Constants {
+		constants[i] = cloneObject(c)
+	}
+
+	return &Bytecode{
+		Instructions:  append(code.Instructions{}, b.Instructions...),
+		Constants:     constants,
+		MaxStackDepth: b.MaxStackDepth,
+		Builtins:      append([]*object.Builtin{}, b.Builtins...),
+	}
 }
 
+// cloneObject 함수는 변경될 수 있는 객체를 재귀적으로 복사합니다.
+func cloneObject(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.CompiledFunction:
+		return &object.CompiledFunction{
+			Instructions:  append(code.Instructions{}, obj.Instructions...),
+			NumLocals:     obj.NumLocals,
+			NumParameters: obj.NumParameters,
+		}
+	case *object.Array:
+		elements := make([]object.Object, len(obj.Elements))
+		for i, e := range obj.Elements {
+			elements[i] = cloneObject(e)
+		}
+		return &object.Array{Elements: elements}
+	case *object.Hash:
+		pairs := make(map[object.HashKey]object.HashPair, len(obj.Pairs))
+		for k, pair := range obj.Pairs {
+			pairs[k] = object.HashPair{Key: cloneObject(pair.Key), Value: cloneObject(pair.Value)}
+		}
+		return &object.Hash{Pairs: pairs}
+	}
+
+	return obj
+}
+
+// newBytecode 함수는 명령어와 상수 풀, 내장 함수 표로 Bytecode 를 만들고 MaxStackDepth 를 계산합니다.
+func newBytecode(ins code.Instructions, constants []object.Object, builtins []*object.Builtin) *Bytecode {
+	// 스택 균형이 맞지 않으면 analyzeStack 은 0 을 반환합니다.
+	depth, _ := analyzeStack(ins)
+
+	return &Bytecode{
+		Instructions:  ins,
+		Constants:     constants,
+		MaxStackDepth: depth,
+		Builtins:      builtins,
+	}
+}
+
+// emit 메서드는 명령어를 생성해 명령어 스트림에 추가하고, 그 시작 위치를 반환합니다.
+// 명령어를 인코딩할 수 없으면 아무것도 덧붙이지 않고 에러를 기록하며, 현재 위치를 반환합니다.
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
-	ins := code.Make(op, operands...)
+	ins, ok := c.makeInstruction(op, operands...)
+	if !ok {
+		return c.Position()
+	}
 	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	if c.TraceFunc != nil {
+		c.TraceFunc(op, operands, pos)
+	}
+
 	return pos
 }
 
+// EmitRaw 메서드는 미리 인코딩된 명령어들을 현재 스코프에 그대로 덧붙입니다.
+// 덧붙인 명령어들을 해석해 마지막/이전 명령어 기록을 갱신하므로, 이후의 removeLastPop 이나
+// changeOperand 도 평소처럼 동작합니다. 해석할 수 없는 바이트가 있으면 아무것도 덧붙이지 않고 에러를 반환합니다.
+func (c *Compiler) EmitRaw(ins code.Instructions) error {
+	type emitted struct {
+		op       code.Opcode
+		operands []int
+		offset   int
+	}
+
+	decoded := []emitted{}
+	err := ins.Iterate(func(offset int, def *code.Definition, operands []int) error {
+		decoded = append(decoded, emitted{code.Opcode(ins[offset]), operands, offset})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	base := c.addInstruction(ins)
+	for _, e := range decoded {
+		c.setLastInstruction(e.op, base+e.offset)
+
+		if c.TraceFunc != nil {
+			c.TraceFunc(e.op, e.operands, base+e.offset)
+		}
+	}
+
+	return nil
+}
+
+// makeInstruction 메서드는 code.MakeSafe 로 명령어를 인코딩합니다. code.Make 처럼 인코딩할 수 없는
+// 명령어를 빈 슬라이스로 바꿔 스트림을 조용히 망가뜨리지 않도록, 실패하면 에러를 기록하고 false 를 반환합니다.
+func (c *Compiler) makeInstruction(op code.Opcode, operands ...int) ([]byte, bool) {
+	ins, err := code.MakeSafe(op, operands...)
+	if err != nil {
+		if def, lookupErr := code.Lookup(byte(op)); lookupErr == nil {
+			err = fmt.Errorf("cannot encode %s: %s", def.Name, err)
+		}
+		if c.err == nil {
+			c.err = err
+		}
+		return nil, false
+	}
+	return ins, true
+}
+
+// emitConstant 메서드는 상수 인덱스를 스택에 올리는 명령어를 내보냅니다.
+// 인덱스가 2바이트 피연산자에 들어가지 않으면 OpConstantWide 를 사용합니다.
+func (c *Compiler) emitConstant(index int) int {
+	if index > math.MaxUint16 {
+		return c.emit(code.OpConstantWide, index)
+	}
+	return c.emit(code.OpConstant, index)
+}
+
+// setLastInstruction 메서드는 방금 내보낸 명령어를 기록하고, 이전 기록은 previousInstruction으로 옮깁니다.
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+// addInstruction 메서드는 인코딩된 명령어를 명령어 스트림 끝에 덧붙이고, 그 시작 위치를 반환합니다.
 func (c *Compiler) addInstruction(ins []byte) int {
-	posNewInstruction := len(c.instructions)
-	c.instructions = append(c.instructions, ins...)
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
 	return posNewInstruction
 }
+
+// optimizeCurrentScope 메서드는 현재 스코프의 명령어 스트림을 최적화합니다.
+// 최적화로 명령어 위치가 바뀌므로 마지막 명령어 기록은 초기화합니다.
+func (c *Compiler) optimizeCurrentScope() {
+	optimized, constants := optimize(c.currentInstructions(), c.constants)
+
+	c.constants = constants
+	c.scopes[c.scopeIndex].instructions = optimized
+	c.scopes[c.scopeIndex].lastInstruction = EmittedInstruction{}
+	c.scopes[c.scopeIndex].previousInstruction = EmittedInstruction{}
+}
+
+// currentInstructions 메서드는 현재 스코프의 명령어 스트림을 반환합니다.
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// Position 메서드는 현재 스코프의 명령어 스트림 길이, 즉 다음에 내보낼 명령어가 놓일 위치를 반환합니다.
+// 점프 대상 위치를 기록하거나 되돌아갈 위치를 계산할 때 사용합니다.
+func (c *Compiler) Position() int {
+	return len(c.currentInstructions())
+}
+
+// enterScope 메서드는 새로운 컴파일 스코프를 만들어 현재 스코프로 전환합니다.
+func (c *Compiler) enterScope() {
+	c.enterScopeWithHint(0)
+}
+
+// instructionBytesPerStatement 는 문장 하나가 평균적으로 만들어 내는 명령어 바이트 수의 추정치로,
+// 함수 본문의 명령어 슬라이스를 미리 할당할 크기를 정하는 데 사용합니다.
+// append 의 재할당은 본문 길이에 대해 로그 횟수뿐이므로 할당 횟수는 거의 줄지 않고,
+// 줄어드는 것은 재할당 때마다 할당되고 복사되는 바이트 수(B/op)입니다.
+const instructionBytesPerStatement = 8
+
+// enterScopeWithHint 메서드는 enterScope 와 같지만, 새 스코프의 명령어 슬라이스를
+// capacity 바이트만큼 미리 할당해 긴 본문을 컴파일할 때 append 의 재할당과 복사를 줄입니다.
+func (c *Compiler) enterScopeWithHint(capacity int) {
+	scope := CompilationScope{
+		instructions:        make(code.Instructions, 0, capacity),
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// leaveScope 메서드는 현재 스코프를 빠져나와 바깥 스코프로 돌아가고,
+// 빠져나온 스코프에서 컴파일된 명령어를 반환합니다.
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+// lastInstructionIs 메서드는 현재 스코프에서 마지막으로 내보낸 명령어가 op 인지 확인합니다.
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+// removeLastPop 메서드는 마지막 OpPop 명령어를 명령어 스트림에서 잘라내고,
+// lastInstruction 을 그 이전 명령어로 되돌립니다.
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+// replaceInstruction 메서드는 pos 위치의 바이트들을 새로운 명령어로 덮어씁니다.
+// 새 명령어는 기존 명령어와 길이가 같아야 합니다.
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// changeOperand 메서드는 opPos 위치에 있는 명령어를 새 피연산자로 다시 인코딩해 교체합니다.
+// 점프 명령어의 임시 피연산자를 실제 오프셋으로 수정(back-patching)할 때 사용합니다.
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	// 점프 피연산자는 2바이트이므로, 이보다 긴 스트림 안의 위치로는 점프할 수 없습니다.
+	if (op == code.OpJump || op == code.OpJumpNotTruthy) && operand > math.MaxUint16 {
+		if c.err == nil {
+			c.err = fmt.Errorf("jump target %d out of range (max %d)", operand, math.MaxUint16)
+		}
+		return
+	}
+
+	newInstruction, ok := c.makeInstruction(op, operand)
+	if !ok {
+		return
+	}
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// replaceLastPopWithReturn 메서드는 마지막 OpPop 을 OpReturnValue 로 바꿔
+// 함수 본문의 마지막 값이 반환되도록 합니다.
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	returnValue, ok := c.makeInstruction(code.OpReturnValue)
+	if !ok {
+		return
+	}
+	c.replaceInstruction(lastPos, returnValue)
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+// checkCapture 메서드는 심볼이 바깥 함수의 지역 바인딩(또는 바깥 함수 자신의 이름)인지 검사합니다.
+// 클로저를 지원하기 전까지는 이런 심볼을 현재 함수의 슬롯으로 읽으면 엉뚱한 값을 가져오므로
+// 에러를 반환합니다. 클로저가 구현되면 이 검사는 자유 변수 처리로 대체되어야 합니다.
+func (c *Compiler) checkCapture(s Symbol) error {
+	if s.Scope != LocalScope && s.Scope != FunctionScope {
+		return nil
+	}
+
+	if own, ok := c.symbolTable.store[s.Name]; ok && own == s {
+		return nil
+	}
+
+	return fmt.Errorf("cannot capture variable %s: closures not supported", s.Name)
+}
+
+// getLocalFastPath 는 피연산자 없는 특수화된 명령어가 있는 지역 심볼 인덱스를 해당 Opcode에 대응시킵니다.
+var getLocalFastPath = []code.Opcode{code.OpGetLocal0, code.OpGetLocal1, code.OpGetLocal2}
+
+// emitGetLocal 메서드는 지역 심볼을 읽는 명령어를 내보냅니다.
+// 인덱스가 0~2 이면 특수화된 OpGetLocalN 을, 그 밖에는 일반 OpGetLocal 을 사용합니다.
+func (c *Compiler) emitGetLocal(index int) {
+	if index < len(getLocalFastPath) {
+		c.emit(getLocalFastPath[index])
+		return
+	}
+	c.emit(code.OpGetLocal, index)
+}
+
+// loadSymbol 메서드는 심볼의 스코프에 맞는 명령어로 심볼의 값을 스택에 올립니다.
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emitGetLocal(s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
@@ -2,11 +2,14 @@ package compiler
 
 import (
 	"fmt"
+	"math"
 	"monkey/ast"
 	"monkey/code"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/token"
+	"strings"
 	"testing"
 )
 
@@ -26,6 +29,22 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+// testStringObject는 주어진 object.Object가 특정 문자열 값을 가진
+// *object.String 타입인지 확인하는 헬퍼 함수입니다.
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%q, want=%q",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
 // testConstants는 컴파일러가 생성한 상수 풀(actual)이
 // 테스트 케이스에서 기대하는 상수(expected)와 일치하는지 확인하는 헬퍼 함수입니다.
 func testConstants(
@@ -47,6 +66,21 @@ func testConstants(
 			if err != nil {
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
+		case string: // 기대값이 string일 경우
+			err := testStringObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testStringObject failed: %s", i, err)
+			}
+		case []code.Instructions: // 기대값이 명령어 목록일 경우 (컴파일된 함수)
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				return fmt.Errorf("constant %d - not a function: %T", i, actual[i])
+			}
+
+			err := testInstructions(constant, fn.Instructions)
+			if err != nil {
+				return fmt.Errorf("constant %d - testInstructions failed: %s", i, err)
+			}
 		}
 	}
 
@@ -74,17 +108,31 @@ func concatInstructions(s []code.Instructions) code.Instructions {
 // compilerTestCase는 컴파일러 테스트를 위한 단일 테스트 케이스의 구조체입니다.
 type compilerTestCase struct {
 	input                string
+	node                 ast.Node            // 설정되면 input 을 파싱하는 대신 이 AST 를 컴파일
 	expectedConstants    []interface{}       // 컴파일 후 기대되는 상수 풀
 	expectedInstructions []code.Instructions // 컴파일 후 기대되는 명령어들
 }
 
+// expressionProgram은 표현식 하나를 표현식문으로 감싼 프로그램을 만드는 헬퍼 함수입니다.
+// 파서가 지원하지 않는 문법이나 파서가 만들 수 없는 잘못된 AST 를 테스트할 때 사용합니다.
+func expressionProgram(expression ast.Expression) *ast.Program {
+	return &ast.Program{
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Expression: expression},
+		},
+	}
+}
+
 // runCompilerTests는 compilerTestCase 슬라이스를 받아
 // 각 케이스에 대해 컴파일러를 실행하고 결과를 검증하는 메인 테스트 러너 함수입니다.
 func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 	t.Helper() // 이 함수가 테스트 헬퍼 함수임을 명시합니다.
 
 	for _, tt := range tests {
-		program := parse(tt.input)
+		var program ast.Node = parse(tt.input)
+		if tt.node != nil {
+			program = tt.node
+		}
 
 		compiler := New()                // 새로운 컴파일러 인스턴스를 생성합니다.
 		err := compiler.Compile(program) // AST를 컴파일합니다.
@@ -101,7 +149,7 @@ func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 		}
 
 		// 생성된 상수 풀이 기대값과 일치하는지 확인합니다.
-		err = testConstants(tt.expectedConstants, bytecode.Constants)
+		err = testConstants(t, tt.expectedConstants, bytecode.Constants)
 		if err != nil {
 			t.Fatalf("testConstants failed: %s", err)
 		}
@@ -117,14 +165,14 @@ func testInstructions(
 	concatted := concatInstructions(expected) // 기대 명령어들을 하나로 합칩니다.
 
 	if len(actual) != len(concatted) {
-		return fmt.Errorf("wrong instructions length.\nwant=%q\ngot =%q",
-			concatted, actual)
+		return fmt.Errorf("wrong instructions length.\n%s",
+			DiffInstructions(concatted, actual))
 	}
 
 	for i, ins := range concatted {
 		if actual[i] != ins {
-			return fmt.Errorf("wrong instruction at %d.\nwant=%q\ngot =%q",
-				i, concatted, actual)
+			return fmt.Errorf("wrong instruction at %d.\n%s",
+				i, DiffInstructions(concatted, actual))
 		}
 	}
 
@@ -134,6 +182,14 @@ func testInstructions(
 // TestIngegerArithmetic는 정수 산술 연산에 대한 컴파일러의 동작을 테스트합니다.
 func TestIngegerArithmetic(t *testing.T) {
 	tests := []compilerTestCase{
+		{
+			input:             "1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
 		{
 			input:             "1 + 2",
 			expectedConstants: []interface{}{1, 2},
@@ -141,9 +197,2919 @@ func TestIngegerArithmetic(t *testing.T) {
 				code.Make(code.OpConstant, 0), // 상수 1 (인덱스 0)
 				code.Make(code.OpConstant, 1), // 상수 2 (인덱스 1)
 				code.Make(code.OpAdd),         // 덧셈 명령어
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 - 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSub), // 뺄셈 명령어
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 * 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMul), // 곱셈 명령어
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "2 / 1",
+			expectedConstants: []interface{}{2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv), // 나눗셈 명령어
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1; 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop), // 첫 번째 표현식문 정리
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop), // 두 번째 표현식문 정리
+			},
+		},
+		{
+			input:             "-1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 파서가 괄호를 별도 노드로 남기지 않으므로, 우선순위는 트리 모양으로만 드러납니다.
+			input:             "(1 + 2) * 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestBooleanExpressions는 불리언 리터럴이 상수 풀 없이 OpTrue/OpFalse로 컴파일되는지 테스트합니다.
+func TestBooleanExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 > 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterThan),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// '<' 는 오른쪽 피연산자(2)를 먼저 컴파일한 뒤 OpGreaterThan 을 내보냅니다.
+			input:             "1 < 2",
+			expectedConstants: []interface{}{2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0), // 상수 2
+				code.Make(code.OpConstant, 1), // 상수 1
+				code.Make(code.OpGreaterThan),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 == 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 != 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpNotEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "true == false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpFalse),
+				code.Make(code.OpEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "!true",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpBang),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestConditionals는 if 표현식이 점프 명령어로 컴파일되는지 테스트합니다.
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { 10 } else { 20 }; 3333;",
+			expectedConstants: []interface{}{10, 20, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 13),
+				// 0010
+				code.Make(code.OpConstant, 1),
+				// 0013
+				code.Make(code.OpPop),
+				// 0014
+				code.Make(code.OpConstant, 2),
+				// 0017
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestGlobalLetStatements는 let 문과 전역 식별자가 OpSetGlobal/OpGetGlobal 로 컴파일되는지 테스트합니다.
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let one = 1;
+			let two = 2;
+			`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+		{
+			input: `
+			let one = 1;
+			one;
+			`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let one = 1;
+			let two = one;
+			two;
+			`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestUndefinedVariable는 정의되지 않은 식별자를 참조하면 컴파일 에러가 발생하는지 테스트합니다.
+func TestUndefinedVariable(t *testing.T) {
+	program := parse("x;")
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if err.Error() != "undefined variable x" {
+		t.Errorf("wrong compiler error. got=%q, want=%q",
+			err.Error(), "undefined variable x")
+	}
+}
+
+// TestStringExpressions는 문자열 리터럴이 상수 풀에 추가되고 OpConstant 로 컴파일되는지 테스트합니다.
+func TestStringExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"monkey"`,
+			expectedConstants: []interface{}{"monkey"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"mon" + "key"`,
+			expectedConstants: []interface{}{"mon", "key"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 비교 연산은 타입과 무관하게 컴파일되며, 실제 비교는 VM 이 피연산자 타입에 따라 처리합니다.
+			input:             `"a" == "b"`,
+			expectedConstants: []interface{}{"a", "b"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"a" != "b"`,
+			expectedConstants: []interface{}{"a", "b"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpNotEqual),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestArrayLiterals는 배열 리터럴이 원소들과 OpArray 로 컴파일되는지 테스트합니다.
+func TestArrayLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[]",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "[1, 2, 3]",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "[1 + 2, 3 - 4, 5 * 6]",
+			expectedConstants: []interface{}{1, 2, 3, 4, 5, 6},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSub),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpMul),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 중첩된 리터럴도 각자 자신의 원소 수를 피연산자로 가집니다.
+			input:             "[[1, 2], [3, 4, 5], {}]",
+			expectedConstants: []interface{}{1, 2, 3, 4, 5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpHash, 0),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "[[1, 2], [3, 4]]",
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestHashLiterals는 해시 리터럴이 정렬된 키/값 쌍과 OpHash 로 컴파일되는지 테스트합니다.
+func TestHashLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "{}",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpHash, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "{1: 2, 3: 4, 5: 6}",
+			expectedConstants: []interface{}{1, 2, 3, 4, 5, 6},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpHash, 6),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "{1: 2 + 3, 4: 5 * 6}",
+			expectedConstants: []interface{}{1, 2, 3, 4, 5, 6},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpMul),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestIndexExpressions는 인덱스 표현식이 대상, 인덱스, OpIndex 순서로 컴파일되는지 테스트합니다.
+func TestIndexExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[1, 2, 3][1 + 1]",
+			expectedConstants: []interface{}{1, 2, 3, 1, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpAdd),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "{1: 2}[2 - 1]",
+			expectedConstants: []interface{}{1, 2, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSub),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestFunctions는 함수 리터럴이 CompiledFunction 상수로 컴파일되는지 테스트합니다.
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { return 5 + 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 마지막 표현식의 값은 암묵적으로 반환됩니다.
+			input: `fn() { 5 + 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { 1; 2 }`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpPop),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestFunctionsWithoutReturnValue는 빈 함수 본문이 OpReturn 으로 컴파일되는지 테스트합니다.
+func TestFunctionsWithoutReturnValue(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// let 문은 값을 남기지 않으므로 마지막 명령어가 OpSetLocal 이어도 OpReturn 으로 끝납니다.
+			input: `fn() { let a = 1 }`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
 			},
 		},
 	}
 
 	runCompilerTests(t, tests)
 }
+
+// TestFunctionCalls는 호출 표현식이 함수, 인자들, OpCall 순서로 컴파일되는지 테스트합니다.
+func TestFunctionCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { 24 }();`,
+			expectedConstants: []interface{}{
+				24,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0), // 리터럴 24
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1), // 컴파일된 함수
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let noArg = fn() { 24 };
+			noArg();
+			`,
+			expectedConstants: []interface{}{
+				24,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0), // 리터럴 24
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1), // 컴파일된 함수
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let manyArg = fn(a, b) { };
+			manyArg(24, 25);
+			`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+				24,
+				25,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let manyArg = fn(a, b, c) { a; b; c };
+			manyArg(24, 25, 26);
+			`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal1),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal2),
+					code.Make(code.OpReturnValue),
+				},
+				24,
+				25,
+				26,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpCall, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestFunctionNumParameters는 매개변수가 지역 바인딩으로 정의되고 개수가 기록되는지 테스트합니다.
+func TestFunctionNumParameters(t *testing.T) {
+	program := parse(`fn(a, b, c) { a; b; c }`)
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	fn, ok := compiler.Bytecode().Constants[0].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 0 is not CompiledFunction. got=%T",
+			compiler.Bytecode().Constants[0])
+	}
+
+	if fn.NumParameters != 3 {
+		t.Errorf("NumParameters wrong. got=%d, want=%d", fn.NumParameters, 3)
+	}
+	if fn.NumLocals != 3 {
+		t.Errorf("NumLocals wrong. got=%d, want=%d", fn.NumLocals, 3)
+	}
+}
+
+// TestLetStatementScopes는 함수 안의 let 바인딩이 지역 명령어로 컴파일되는지 테스트합니다.
+func TestLetStatementScopes(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let num = 55;
+			fn() { num }
+			`,
+			expectedConstants: []interface{}{
+				55,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			fn() {
+				let num = 55;
+				num
+			}
+			`,
+			expectedConstants: []interface{}{
+				55,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			fn() {
+				let a = 55;
+				let b = 77;
+				a + b
+			}
+			`,
+			expectedConstants: []interface{}{
+				55,
+				77,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpGetLocal1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestFunctionNumLocals는 컴파일된 함수가 자신의 지역 바인딩 개수를 기록하는지 테스트합니다.
+func TestFunctionNumLocals(t *testing.T) {
+	program := parse(`
+	fn() {
+		let a = 1;
+		let inner = fn() { let b = 2; b };
+		a
+	}
+	`)
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	// 상수 풀: 1, 2, inner, outer
+	constants := compiler.Bytecode().Constants
+	if len(constants) != 4 {
+		t.Fatalf("wrong number of constants. got=%d, want=%d", len(constants), 4)
+	}
+
+	inner, ok := constants[2].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 2 is not CompiledFunction. got=%T", constants[2])
+	}
+	if inner.NumLocals != 1 {
+		t.Errorf("inner NumLocals wrong. got=%d, want=%d", inner.NumLocals, 1)
+	}
+
+	// 바깥 함수는 a 와 inner 두 개의 지역 바인딩을 가집니다.
+	outer, ok := constants[3].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 3 is not CompiledFunction. got=%T", constants[3])
+	}
+	if outer.NumLocals != 2 {
+		t.Errorf("outer NumLocals wrong. got=%d, want=%d", outer.NumLocals, 2)
+	}
+}
+
+// TestBuiltins는 내장 함수 참조가 object.Builtins 인덱스와 함께 OpGetBuiltin 으로 컴파일되는지 테스트합니다.
+func TestBuiltins(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			len([]);
+			push([], 1);
+			`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetBuiltin, 5),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCall, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { len([]) }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestRecursiveFunctions는 재귀 호출이 전역 조회가 아닌 OpCurrentClosure 로 컴파일되는지 테스트합니다.
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let countDown = fn(x) { countDown(x - 1); };
+			countDown(1);
+			`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					// 전역 함수의 자기 호출은 자신의 전역 인덱스를 읽습니다.
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let wrapper = fn() {
+				let countDown = fn(x) { countDown(x - 1); };
+				countDown(1);
+			};
+			wrapper();
+			`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpCurrentClosure),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// let 은 값보다 먼저 심볼을 정의하므로, 본문의 fib 는 자신의 전역 인덱스(1)로 해석됩니다.
+			input: `
+			let a = 1;
+			let fib = fn(n) { fib(n - a) };
+			`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 1),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestDeduplicateConstants는 중복 제거 옵션을 켜면 같은 리터럴이 하나의 상수를 공유하는지 테스트합니다.
+func TestDeduplicateConstants(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 1 + 1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 값이 같아도 타입이 다르면 별개의 상수입니다.
+			input:             `1; "1"; "1"`,
+			expectedConstants: []interface{}{1, "1"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		compiler.DeduplicateConstants = true
+
+		err := compiler.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := compiler.Bytecode()
+
+		err = testInstructions(tt.expectedInstructions, bytecode.Instructions)
+		if err != nil {
+			t.Fatalf("testInstructions failed: %s", err)
+		}
+
+		err = testConstants(t, tt.expectedConstants, bytecode.Constants)
+		if err != nil {
+			t.Fatalf("testConstants failed: %s", err)
+		}
+	}
+}
+
+// TestNewWithState는 심볼 테이블과 상수 풀을 공유하는 컴파일러끼리 전역 바인딩이 유지되는지 테스트합니다.
+func TestNewWithState(t *testing.T) {
+	symbolTable := NewSymbolTable()
+	constants := []object.Object{}
+
+	first := NewWithState(symbolTable, constants)
+	err := first.Compile(parse("let a = 1;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	constants = first.Bytecode().Constants
+
+	second := NewWithState(symbolTable, constants)
+	err = second.Compile(parse("a + 2;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bytecode := second.Bytecode()
+
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}, bytecode.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	// 두 번째 컴파일러의 상수 풀에는 첫 번째 컴파일러의 상수가 그대로 남아 있어야 합니다.
+	err = testConstants(t, []interface{}{1, 2}, bytecode.Constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+// TestUnknownOperator는 지원하지 않는 연산자를 만나면 컴파일 에러를 반환하는지 테스트합니다.
+// 파서가 만들지 않는 연산자이므로 AST 를 직접 구성합니다.
+func TestUnknownOperator(t *testing.T) {
+	tests := []struct {
+		expression ast.Expression
+		expected   string
+	}{
+		{
+			&ast.InfixExpression{
+				Left:     &ast.IntegerLiteral{Value: 1},
+				Operator: "@@",
+				Right:    &ast.IntegerLiteral{Value: 2},
+			},
+			"unknown operator @@",
+		},
+		{
+			&ast.PrefixExpression{
+				Operator: "~",
+				Right:    &ast.IntegerLiteral{Value: 1},
+			},
+			"unknown operator ~",
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(expressionProgram(tt.expression))
+		if err == nil {
+			t.Fatalf("expected compiler error but resulted in none.")
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. got=%q, want=%q", err.Error(), tt.expected)
+		}
+	}
+}
+
+// TestWideConstantIndex는 상수 인덱스가 2바이트 범위를 넘으면 OpConstantWide 가 사용되는지 테스트합니다.
+func TestWideConstantIndex(t *testing.T) {
+	compiler := New()
+	for i := 0; i <= math.MaxUint16; i++ {
+		compiler.addConstant(&object.Integer{Value: int64(i)})
+	}
+
+	err := compiler.Compile(parse("1"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpConstantWide, math.MaxUint16+1),
+		code.Make(code.OpPop),
+	}, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+// TestUnreachableCodeAfterReturn는 블록 최상위 return 뒤의 문장이 컴파일되지 않는지 테스트합니다.
+func TestUnreachableCodeAfterReturn(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { return 1; 2; 3 }`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 조건부 return 뒤의 문장은 실행될 수 있으므로 그대로 컴파일됩니다.
+			input: `fn() { if (true) { return 1; }; 2 }`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpTrue),
+					code.Make(code.OpJumpNotTruthy, 11),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+					code.Make(code.OpJump, 12),
+					code.Make(code.OpNull),
+					code.Make(code.OpPop),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestTraceFunc는 TraceFunc 가 내보내는 명령어마다 올바른 위치와 함께 호출되는지 테스트합니다.
+func TestTraceFunc(t *testing.T) {
+	type trace struct {
+		op       code.Opcode
+		operands []int
+		pos      int
+	}
+
+	traces := []trace{}
+
+	compiler := New()
+	compiler.TraceFunc = func(op code.Opcode, operands []int, pos int) {
+		traces = append(traces, trace{op, operands, pos})
+	}
+
+	err := compiler.Compile(parse("1 + 2"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []trace{
+		{code.OpConstant, []int{0}, 0},
+		{code.OpConstant, []int{1}, 3},
+		{code.OpAdd, []int{}, 6},
+		{code.OpPop, []int{}, 7},
+	}
+
+	if len(traces) != len(expected) {
+		t.Fatalf("wrong number of traces. want=%d, got=%d", len(expected), len(traces))
+	}
+
+	for i, want := range expected {
+		got := traces[i]
+		if got.op != want.op || got.pos != want.pos {
+			t.Errorf("trace %d wrong. want=%d@%d, got=%d@%d", i, want.op, want.pos, got.op, got.pos)
+		}
+		if len(got.operands) != len(want.operands) {
+			t.Errorf("trace %d operands wrong. want=%v, got=%v", i, want.operands, got.operands)
+			continue
+		}
+		for j, o := range want.operands {
+			if got.operands[j] != o {
+				t.Errorf("trace %d operand %d wrong. want=%d, got=%d", i, j, o, got.operands[j])
+			}
+		}
+	}
+
+	// 추적 함수가 있어도 내보낸 바이트는 같아야 합니다.
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+// TestDuplicateLetBindings는 같은 스코프에서 let 으로 같은 이름을 다시 정의하면 에러가 나고,
+// 안쪽 스코프에서 가리는 것은 허용되는지 테스트합니다.
+func TestDuplicateLetBindings(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("let x = 1; let x = 2;"))
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+	if err.Error() != "variable x is already defined in this scope" {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+
+	tests := []compilerTestCase{
+		{
+			input: `
+			let x = 1;
+			fn() { let x = 2; x }
+			`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestReset은 Reset(false) 후 다시 컴파일한 결과가 새 컴파일러의 결과와 같은지,
+// 그리고 이전에 받은 Bytecode 와 NewWithState 에 넘긴 심볼 테이블이 덮어써지지 않는지 테스트합니다.
+func TestReset(t *testing.T) {
+	inputs := []string{
+		"let x = 1; x + 2",
+		`let y = "a"; fn(a) { a }(y)`,
+		`"b"; 1`,
+	}
+
+	state := New()
+	err := state.Compile(parse("let g = 1;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	symbolTable := state.SymbolTable()
+
+	reused := NewWithState(symbolTable, state.Bytecode().Constants)
+	err = reused.Compile(parse("g + 2"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	withState := reused.Bytecode()
+
+	var got, want []*Bytecode
+	for _, input := range inputs {
+		reused.Reset(false)
+		err := reused.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		got = append(got, reused.Bytecode())
+
+		fresh := New()
+		err = fresh.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		want = append(want, fresh.Bytecode())
+	}
+
+	for i := range inputs {
+		if got[i].String() != want[i].String() {
+			t.Errorf("wrong bytecode for input %d.\nwant=%s\ngot =%s", i, want[i], got[i])
+		}
+	}
+
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}, withState.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	err = testConstants(t, []interface{}{1, 2}, withState.Constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+
+	if _, ok := symbolTable.Resolve("g"); !ok {
+		t.Errorf("global g lost from the table passed to NewWithState")
+	}
+
+	// 최상위 명령어 슬라이스는 재사용되므로 Reset 자체는 할당하지 않습니다.
+	allocs := testing.AllocsPerRun(10, func() { reused.Reset(true) })
+	if allocs != 0 {
+		t.Errorf("Reset(true) allocated. allocs=%v", allocs)
+	}
+}
+
+// TestResetKeepState는 Reset(true) 후에도 이전 컴파일의 전역 바인딩과 상수를 계속 쓸 수 있는지 테스트합니다.
+func TestResetKeepState(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("let x = 1;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	compiler.Reset(true)
+	err = compiler.Compile(parse("x"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expectedInstructions := []code.Instructions{
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpPop),
+	}
+
+	bytecode := compiler.Bytecode()
+	err = testInstructions(expectedInstructions, bytecode.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	err = testConstants(t, []interface{}{1}, bytecode.Constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+// TestIntegerLiteralOutOfRange는 int64 범위를 넘는 정수 리터럴이 컴파일 에러가 되는지 테스트합니다.
+func TestIntegerLiteralOutOfRange(t *testing.T) {
+	literal := "9223372036854775808" // math.MaxInt64 + 1
+	program := expressionProgram(&ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: literal},
+		Value: math.MinInt64,
+	})
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if err.Error() != "integer literal out of range: "+literal {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+}
+
+// TestMaxGlobals는 MaxGlobals 를 넘는 전역 바인딩이 컴파일 에러가 되는지 테스트합니다.
+func TestMaxGlobals(t *testing.T) {
+	compiler := New()
+	if compiler.MaxGlobals != 65536 {
+		t.Errorf("wrong default MaxGlobals. want=65536, got=%d", compiler.MaxGlobals)
+	}
+
+	compiler.MaxGlobals = 2
+	err := compiler.Compile(parse("let a = 1; let b = 2;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err = compiler.Compile(parse("let c = 3;"))
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if err.Error() != "too many global bindings (max 2)" {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+
+	// 거부된 바인딩은 심볼 테이블에 남지 않습니다.
+	if _, ok := compiler.SymbolTable().Resolve("c"); ok {
+		t.Errorf("rejected binding c was defined")
+	}
+	if n := compiler.SymbolTable().numDefinitions; n != 2 {
+		t.Errorf("wrong numDefinitions. want=2, got=%d", n)
+	}
+
+	// 지역 바인딩은 전역 한도의 영향을 받지 않습니다.
+	compiler = New()
+	compiler.MaxGlobals = 1
+	err = compiler.Compile(parse("let f = fn() { let a = 1; let b = 2; };"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+}
+
+// TestChainedIndexAndCall은 연쇄된 인덱스/호출 표현식에서 왼쪽이 먼저 완전히 컴파일된 뒤
+// 바깥 연산이 내보내지는지 테스트합니다.
+func TestChainedIndexAndCall(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `let myArray = [[1, 2]]; myArray[0][1]`,
+			expectedConstants: []interface{}{1, 2, 0, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex), // myArray[0]
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpIndex), // (myArray[0])[1]
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `let adder = fn(a) { fn(b) { b } }; adder(1)(2)`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+				2,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 1), // adder(1)
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpCall, 1), // (adder(1))(2)
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestPosition은 Position 이 emit 할 때마다 내보낸 명령어의 길이만큼 늘어나는지 테스트합니다.
+func TestPosition(t *testing.T) {
+	compiler := New()
+	if compiler.Position() != 0 {
+		t.Fatalf("wrong initial position. got=%d", compiler.Position())
+	}
+
+	tests := []struct {
+		op       code.Opcode
+		operands []int
+	}{
+		{code.OpConstant, []int{0}},
+		{code.OpPop, []int{}},
+		{code.OpCall, []int{2}},
+		{code.OpClosure, []int{1, 0}},
+	}
+
+	for _, tt := range tests {
+		before := compiler.Position()
+		pos := compiler.emit(tt.op, tt.operands...)
+		if pos != before {
+			t.Errorf("emit returned %d, want %d", pos, before)
+		}
+
+		want := before + len(code.Make(tt.op, tt.operands...))
+		if compiler.Position() != want {
+			t.Errorf("wrong position after %d. want=%d, got=%d",
+				tt.op, want, compiler.Position())
+		}
+	}
+
+	// 새 스코프에 들어가면 그 스코프의 명령어 스트림 기준으로 위치를 셉니다.
+	compiler.enterScope()
+	if compiler.Position() != 0 {
+		t.Errorf("wrong position in new scope. got=%d", compiler.Position())
+	}
+}
+
+// TestHashLiteralIntegerKeyOrder는 정수 키만 있는 해시 리터럴이 키의 크기 순으로 컴파일되고,
+// 그 밖의 경우에는 문자열 순서를 따르는지 테스트합니다.
+func TestHashLiteralIntegerKeyOrder(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `{10: "b", 2: "a"}`,
+			expectedConstants: []interface{}{2, "a", 10, "b"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `{10: "b", "2": "a"}`,
+			expectedConstants: []interface{}{10, "b", "2", "a"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	// 같은 키가 여러 번 나오면 값의 순서로 정렬합니다. map 순회 순서가 매번 달라지므로 여러 번 확인합니다.
+	duplicates := []compilerTestCase{
+		{
+			input:             `{1: "b", 1: "a"}`,
+			expectedConstants: []interface{}{1, "a", 1, "b"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `{"k": 2, "k": 1}`,
+			expectedConstants: []interface{}{"k", 1, "k", 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	for i := 0; i < 20; i++ {
+		runCompilerTests(t, duplicates)
+	}
+}
+
+// TestRegisterBuiltin은 등록한 사용자 내장 함수가 자신의 인덱스로 OpGetBuiltin 을 내보내고,
+// 그 함수가 object.Builtins 가 아닌 이 컴파일러의 Bytecode.Builtins 에만 들어가는지 테스트합니다.
+func TestRegisterBuiltin(t *testing.T) {
+	original := len(object.Builtins)
+
+	double := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	}}
+
+	compiler := New()
+	err := compiler.RegisterBuiltin("double", double)
+	if err != nil {
+		t.Fatalf("RegisterBuiltin failed: %s", err)
+	}
+
+	if len(object.Builtins) != original {
+		t.Fatalf("RegisterBuiltin changed object.Builtins")
+	}
+
+	index := original
+	err = compiler.Compile(parse("double(2); len([]);"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expectedInstructions := []code.Instructions{
+		code.Make(code.OpGetBuiltin, index),
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpCall, 1),
+		code.Make(code.OpPop),
+		code.Make(code.OpGetBuiltin, 0),
+		code.Make(code.OpArray, 0),
+		code.Make(code.OpCall, 1),
+		code.Make(code.OpPop),
+	}
+
+	bytecode := compiler.Bytecode()
+	err = testInstructions(expectedInstructions, bytecode.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	if len(bytecode.Builtins) != original+1 || bytecode.Builtins[index] != double {
+		t.Fatalf("double not in Bytecode.Builtins at index %d", index)
+	}
+	if bytecode.Builtins[0] != object.Builtins[0].Builtin {
+		t.Errorf("Bytecode.Builtins does not start with object.Builtins")
+	}
+
+	// 같은 이름을 다시 등록하거나 기본 내장 함수의 이름을 쓰면 에러입니다.
+	for _, name := range []string{"double", "len"} {
+		err = compiler.RegisterBuiltin(name, double)
+		if err == nil {
+			t.Errorf("expected error for duplicate builtin %s, got none", name)
+			continue
+		}
+		if err.Error() != "builtin "+name+" is already defined" {
+			t.Errorf("wrong error. got=%q", err.Error())
+		}
+	}
+
+	// 다른 컴파일러에는 등록되지 않습니다.
+	err = New().Compile(parse("double(2);"))
+	if err == nil || err.Error() != "undefined variable double" {
+		t.Errorf("double leaked into a new compiler. err=%v", err)
+	}
+
+	// NewWithState 로 심볼 테이블을 넘기면 등록한 함수도 함께 넘어갑니다.
+	next := NewWithState(compiler.SymbolTable(), compiler.Constants())
+	if builtins := next.Bytecode().Builtins; len(builtins) != original+1 || builtins[index] != double {
+		t.Errorf("double not carried over by NewWithState")
+	}
+}
+
+// TestBooleanAndNullNotInConstantPool은 true, false, null 이 상수 풀을 거치지 않고
+// 전용 Opcode 로만 컴파일되는지 테스트합니다.
+func TestBooleanAndNullNotInConstantPool(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[true, false]",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpFalse),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// else 가 없는 if 의 값인 null 도 OpNull 로 만들어집니다.
+			input:             "if (false) { true }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpFalse),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 8),
+				// 0004
+				code.Make(code.OpTrue),
+				// 0005
+				code.Make(code.OpJump, 9),
+				// 0008
+				code.Make(code.OpNull),
+				// 0009
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestCompileStatement는 문장을 하나씩 CompileStatement 로 넘긴 결과가
+// 하나의 프로그램으로 컴파일한 결과와 같은지 테스트합니다.
+func TestCompileStatement(t *testing.T) {
+	input := `let x = 1; let f = fn(a) { a + x }; f(2);`
+
+	whole := New()
+	err := whole.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	program := parse(input)
+	if len(program.Statements) != 3 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	streamed := New()
+	for _, stmt := range program.Statements {
+		err := streamed.CompileStatement(stmt)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+	}
+
+	want := whole.Bytecode().String()
+	got := streamed.Bytecode().String()
+	if got != want {
+		t.Errorf("streamed bytecode differs.\nwant=%s\ngot =%s", want, got)
+	}
+}
+
+// TestTooManyCallArguments는 1바이트 피연산자에 담을 수 없는 수의 인자를 가진 호출이
+// 잘린 OpCall 대신 컴파일 에러가 되는지 테스트합니다.
+func TestTooManyCallArguments(t *testing.T) {
+	args := make([]ast.Expression, 256)
+	for i := range args {
+		args[i] = &ast.IntegerLiteral{Value: int64(i)}
+	}
+
+	program := expressionProgram(&ast.CallExpression{
+		Function:  &ast.Identifier{Value: "len"},
+		Arguments: args,
+	})
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if err.Error() != "too many arguments in call (max 255)" {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+}
+
+// TestKeepLastValue는 KeepLastValue 가 마지막 표현식문의 OpPop 만 생략하는지 테스트합니다.
+func TestKeepLastValue(t *testing.T) {
+	tests := []struct {
+		input                string
+		keepLastValue        bool
+		expectedInstructions []code.Instructions
+	}{
+		{
+			input:         "1; 2; 3",
+			keepLastValue: false,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:         "1; 2; 3",
+			keepLastValue: true,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+			},
+		},
+		{
+			// 마지막 문장이 let 이면 남길 값이 없습니다.
+			input:         "1; let a = 2;",
+			keepLastValue: true,
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		compiler.KeepLastValue = tt.keepLastValue
+
+		err := compiler.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err = testInstructions(tt.expectedInstructions, compiler.Bytecode().Instructions)
+		if err != nil {
+			t.Errorf("input %q, KeepLastValue=%t: %s", tt.input, tt.keepLastValue, err)
+		}
+	}
+}
+
+// TestPrefixPlus는 단항 + 가 피연산자만 컴파일하고 추가 명령어를 내보내지 않는지 테스트합니다.
+func TestPrefixPlus(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			node: expressionProgram(&ast.PrefixExpression{
+				Operator: "+",
+				Right:    &ast.IntegerLiteral{Value: 5},
+			}),
+			expectedConstants: []interface{}{5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestCaptureWithoutClosures는 클로저가 없는 동안 바깥 함수의 지역 바인딩을 참조하면
+// 컴파일 에러가 되는지 테스트합니다.
+func TestCaptureWithoutClosures(t *testing.T) {
+	inputs := []string{
+		"fn(a) { fn(b) { a + b } }",
+		"fn() { let a = 1; fn() { a } }",
+	}
+
+	for _, input := range inputs {
+		compiler := New()
+		err := compiler.Compile(parse(input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but resulted in none.", input)
+		}
+
+		if err.Error() != "cannot capture variable a: closures not supported" {
+			t.Errorf("wrong compiler error. got=%q", err.Error())
+		}
+	}
+
+	// 전역 바인딩과 내장 함수는 바깥 스코프에 있어도 그대로 참조할 수 있습니다.
+	compiler := New()
+	err := compiler.Compile(parse("let g = 1; fn() { fn() { len([g]) } }"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+}
+
+// TestBareBlockStatement는 if 밖에 단독으로 놓인 블록 문장도 안의 문장들을 차례로 컴파일하는지 테스트합니다.
+func TestBareBlockStatement(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			node: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.BlockStatement{
+						Statements: []ast.Statement{
+							&ast.ExpressionStatement{Expression: &ast.IntegerLiteral{Value: 1}},
+							&ast.ExpressionStatement{Expression: &ast.IntegerLiteral{Value: 2}},
+						},
+					},
+				},
+			},
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// unsupportedNode 는 컴파일러가 모르는 AST 노드를 흉내 냅니다.
+type unsupportedNode struct{}
+
+func (n *unsupportedNode) TokenLiteral() string { return "?" }
+func (n *unsupportedNode) String() string       { return "?" }
+
+// TestUnknownNodeType은 처리하지 않는 AST 노드가 조용히 무시되지 않고 에러가 되는지 테스트합니다.
+func TestUnknownNodeType(t *testing.T) {
+	// ast.Expression 의 노드 표시 메서드는 공개되지 않았으므로 Compile 에 직접 넘깁니다.
+	compiler := New()
+	err := compiler.Compile(&unsupportedNode{})
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if err.Error() != "unknown AST node type: *compiler.unsupportedNode" {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+}
+
+// TestLogicalOperators는 && 와 || 가 왼쪽 피연산자로 결과가 정해지면
+// 왼쪽 값을 남긴 채 오른쪽 피연산자를 건너뛰도록 점프를 내보내는지 테스트합니다.
+func TestLogicalOperators(t *testing.T) {
+	logical := func(operator string) *ast.Program {
+		return expressionProgram(&ast.InfixExpression{
+			Left:     &ast.Boolean{Value: true},
+			Operator: operator,
+			Right:    &ast.IntegerLiteral{Value: 1},
+		})
+	}
+
+	tests := []compilerTestCase{
+		{
+			node:              logical("&&"),
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpDup),
+				// 0002 왼쪽이 거짓이면 왼쪽 값을 남기고 오른쪽을 건너뜀
+				code.Make(code.OpJumpNotTruthy, 9),
+				// 0005
+				code.Make(code.OpPop),
+				// 0006
+				code.Make(code.OpConstant, 0),
+				// 0009
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			node:              logical("||"),
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpDup),
+				// 0002 왼쪽이 거짓일 때만 오른쪽을 평가
+				code.Make(code.OpJumpNotTruthy, 8),
+				// 0005 왼쪽이 참이면 왼쪽 값을 남기고 끝으로
+				code.Make(code.OpJump, 12),
+				// 0008
+				code.Make(code.OpPop),
+				// 0009
+				code.Make(code.OpConstant, 0),
+				// 0012
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	// 두 경로 모두 스택에 값 하나를 남겨야 합니다.
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(tt.node)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err = Verify(compiler.Bytecode())
+		if err != nil {
+			t.Errorf("%s: Verify failed: %s", tt.node, err)
+		}
+	}
+}
+
+// TestAddConstant는 미리 넣어 둔 상수의 인덱스가 이후 컴파일에도 유지되는지 테스트합니다.
+func TestAddConstant(t *testing.T) {
+	compiler := New()
+	config := &object.String{Value: "production"}
+
+	index := compiler.AddConstant(config)
+	if index != 0 {
+		t.Fatalf("wrong constant index. want=0, got=%d", index)
+	}
+
+	err := compiler.Compile(parse("1 + 2"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	constants := compiler.Constants()
+	if constants[index] != config {
+		t.Errorf("pre-registered constant moved. got=%+v", constants[index])
+	}
+
+	err = testConstants(t, []interface{}{"production", 1, 2}, constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+// TestEmptyProgram은 빈 입력이 에러 없이 명령어와 상수가 없는 바이트코드로 컴파일되는지 테스트합니다.
+func TestEmptyProgram(t *testing.T) {
+	compiler := New()
+	compiler.KeepLastValue = true
+	compiler.Optimize = true
+
+	err := compiler.Compile(parse(""))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bytecode := compiler.Bytecode()
+	if len(bytecode.Instructions) != 0 {
+		t.Errorf("expected no instructions, got=%q", bytecode.Instructions)
+	}
+	if len(bytecode.Constants) != 0 {
+		t.Errorf("expected no constants, got=%d", len(bytecode.Constants))
+	}
+}
+
+// TestMalformedHashLiteral은 값이 빠진 쌍을 가진 해시 리터럴이 잘못된 OpHash 대신
+// 컴파일 에러가 되는지 테스트합니다.
+func TestMalformedHashLiteral(t *testing.T) {
+	program := expressionProgram(&ast.HashLiteral{
+		Pairs: map[ast.Expression]ast.Expression{
+			&ast.IntegerLiteral{Value: 1}: &ast.IntegerLiteral{Value: 2},
+			&ast.IntegerLiteral{Value: 3}: nil,
+		},
+	})
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if err.Error() != "malformed hash literal" {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+}
+
+// TestNewIncremental은 소스의 한 문장만 바꿔 다시 컴파일해도
+// 바뀌지 않은 상수의 인덱스가 유지되는지 테스트합니다.
+func TestNewIncremental(t *testing.T) {
+	first := New()
+	err := first.Compile(parse(`let a = 1; let b = "x"; a + 2;`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	prev := first.Bytecode()
+
+	second := NewIncremental(prev)
+	err = second.Compile(parse(`let a = 1; let b = "y"; a + 2;`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := second.Bytecode()
+
+	expectedInstructions := []code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpSetGlobal, 0),
+		code.Make(code.OpConstant, 3), // 새 상수 "y" 만 끝에 덧붙습니다.
+		code.Make(code.OpSetGlobal, 1),
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 2),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}
+
+	err = testInstructions(expectedInstructions, bytecode.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	err = testConstants(t, []interface{}{1, "x", 2, "y"}, bytecode.Constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+
+	// 이전 바이트코드의 상수 풀은 건드리지 않습니다.
+	if len(prev.Constants) != 3 {
+		t.Errorf("previous constants modified. got=%d", len(prev.Constants))
+	}
+}
+
+// TestStrictDivision은 StrictDivision 이 리터럴 0 으로 나누는 경우에만 컴파일 에러를 내는지 테스트합니다.
+func TestStrictDivision(t *testing.T) {
+	compiler := New()
+	compiler.StrictDivision = true
+	err := compiler.Compile(parse("10 / 0"))
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+	if err.Error() != "division by zero" {
+		t.Errorf("wrong compiler error. got=%q", err.Error())
+	}
+
+	// 나누는 수가 리터럴이 아니면 실행 시점의 검사에 맡깁니다.
+	compiler = New()
+	compiler.StrictDivision = true
+	err = compiler.Compile(parse("let x = 0; 10 / x"))
+	if err != nil {
+		t.Errorf("unexpected compiler error: %s", err)
+	}
+
+	// 엄격 모드가 아니면 리터럴 0 도 그대로 컴파일합니다.
+	compiler = New()
+	err = compiler.Compile(parse("10 / 0"))
+	if err != nil {
+		t.Errorf("unexpected compiler error: %s", err)
+	}
+}
+
+// TestModuloOperator는 % 연산자가 두 피연산자 뒤에 OpMod 로 컴파일되는지 테스트합니다.
+func TestModuloOperator(t *testing.T) {
+	// 파서가 % 를 지원하지 않으므로 AST 를 직접 만듭니다.
+	tests := []compilerTestCase{
+		{
+			node: expressionProgram(&ast.InfixExpression{
+				Left:     &ast.IntegerLiteral{Value: 10},
+				Operator: "%",
+				Right:    &ast.IntegerLiteral{Value: 3},
+			}),
+			expectedConstants: []interface{}{10, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMod),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestWalkConstants는 WalkConstants 가 함수 본문의 상수를 포함해 모든 상수를 한 번씩 방문하는지 테스트합니다.
+func TestWalkConstants(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse(`let f = fn() { "inner" + 1 }; f() + 2;`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	counts := map[object.ObjectType]int{}
+	visited := []int{}
+	compiler.Bytecode().WalkConstants(func(index int, obj object.Object) {
+		counts[obj.Type()]++
+		visited = append(visited, index)
+	})
+
+	if len(visited) != 4 {
+		t.Fatalf("wrong number of visited constants. want=4, got=%d", len(visited))
+	}
+	for i, index := range visited {
+		if index != i {
+			t.Errorf("constants visited out of order. got=%v", visited)
+			break
+		}
+	}
+
+	if counts[object.STRING_OBJ] != 1 || counts[object.INTEGER_OBJ] != 2 ||
+		counts[object.COMPILED_FUNCTION_OBJ] != 1 {
+		t.Errorf("wrong constant types visited. got=%v", counts)
+	}
+}
+
+// TestIfExpressionAsCallArgument는 호출 인자로 쓰인 if 표현식의 점프가
+// OpCall 보다 먼저, 인자 위치에 그대로 내보내지는지 테스트합니다.
+func TestIfExpressionAsCallArgument(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `let x = true; puts(if (x) { 1 } else { 2 })`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpSetGlobal, 0),
+				// 0004
+				code.Make(code.OpGetBuiltin, 1),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpJumpNotTruthy, 18),
+				// 0012
+				code.Make(code.OpConstant, 0),
+				// 0015
+				code.Make(code.OpJump, 21),
+				// 0018
+				code.Make(code.OpConstant, 1),
+				// 0021
+				code.Make(code.OpCall, 1),
+				// 0023
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestGreaterEqual은 >= 가 OpGreaterEqual 로, <= 가 피연산자 순서를 바꾼 OpGreaterEqual 로
+// 컴파일되는지 테스트합니다.
+func TestGreaterEqual(t *testing.T) {
+	// 파서가 >= 와 <= 를 지원하지 않으므로 AST 를 직접 만듭니다.
+	tests := []compilerTestCase{
+		{
+			node: expressionProgram(&ast.InfixExpression{
+				Left:     &ast.IntegerLiteral{Value: 1},
+				Operator: ">=",
+				Right:    &ast.IntegerLiteral{Value: 2},
+			}),
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			node: expressionProgram(&ast.InfixExpression{
+				Left:     &ast.IntegerLiteral{Value: 1},
+				Operator: "<=",
+				Right:    &ast.IntegerLiteral{Value: 2},
+			}),
+			expectedConstants: []interface{}{2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterEqual),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestSymbolTableAccessor는 컴파일 후 꺼낸 심볼 테이블에서 전역 바인딩을 찾을 수 있고,
+// 그 테이블을 NewWithState 에 넘겨 이어서 컴파일할 수 있는지 테스트합니다.
+func TestSymbolTableAccessor(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("let x = 1"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	symbolTable := compiler.SymbolTable()
+	symbol, ok := symbolTable.Resolve("x")
+	if !ok {
+		t.Fatalf("x not resolvable")
+	}
+
+	expected := Symbol{Name: "x", Scope: GlobalScope, Index: 0}
+	if symbol != expected {
+		t.Errorf("expected x to resolve to %+v, got=%+v", expected, symbol)
+	}
+
+	next := NewWithState(symbolTable, compiler.Constants())
+	err = next.Compile(parse("x"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+}
+
+// TestCoveredOpcodes는 CoveredOpcodes 가 최상위 명령어와 함수 본문에 나타난 Opcode만 정확히 보고하는지 테스트합니다.
+func TestCoveredOpcodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []code.Opcode
+	}{
+		{"1 + 2", []code.Opcode{code.OpConstant, code.OpAdd, code.OpPop}},
+		{
+			"fn() { true }",
+			[]code.Opcode{code.OpConstant, code.OpPop, code.OpTrue, code.OpReturnValue},
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		covered := compiler.Bytecode().CoveredOpcodes()
+		if len(covered) != len(tt.expected) {
+			t.Errorf("wrong number of opcodes for %q. want=%d, got=%d (%v)",
+				tt.input, len(tt.expected), len(covered), covered)
+		}
+
+		for _, op := range tt.expected {
+			if !covered[op] {
+				t.Errorf("opcode %d not reported as covered for %q", op, tt.input)
+			}
+		}
+	}
+}
+
+// TestCompileExpression은 CompileExpression 이 OpPop 없이 결과를 스택에 남기는지 테스트합니다.
+func TestCompileExpression(t *testing.T) {
+	expr := parse("1 + 2").Statements[0].(*ast.ExpressionStatement).Expression
+
+	compiler := New()
+	err := compiler.CompileExpression(expr)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expectedInstructions := []code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+	}
+
+	bytecode := compiler.Bytecode()
+	err = testInstructions(expectedInstructions, bytecode.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	if bytecode.MaxStackDepth != 2 {
+		t.Errorf("wrong MaxStackDepth. want=2, got=%d", bytecode.MaxStackDepth)
+	}
+}
+
+// TestBytecodeClone은 복사본의 명령어와 상수를 바꿔도 원본이 그대로인지 테스트합니다.
+func TestBytecodeClone(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("let f = fn() { 1 }; f();"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	compiler.AddConstant(&object.Array{Elements: []object.Object{&object.Integer{Value: 1}}})
+
+	original := compiler.Bytecode()
+	want := original.String()
+
+	clone := original.Clone()
+	if clone.String() != want {
+		t.Fatalf("clone differs from original.\nwant=%s\ngot =%s", want, clone)
+	}
+
+	clone.Instructions[0] = byte(code.OpPop)
+	clone.Constants[1].(*object.CompiledFunction).Instructions[0] = byte(code.OpPop)
+	clone.Constants[2].(*object.Array).Elements[0] = &object.Integer{Value: 2}
+	clone.Constants[0] = &object.Integer{Value: 99}
+
+	if original.String() != want {
+		t.Errorf("original changed after mutating clone.\nwant=%s\ngot =%s", want, original)
+	}
+
+	arr := original.Constants[2].(*object.Array)
+	if arr.Elements[0].(*object.Integer).Value != 1 {
+		t.Errorf("original array constant changed. got=%s", arr.Inspect())
+	}
+}
+
+// TestGetLocalFastPath는 0~2번 지역 심볼이 피연산자 없는 OpGetLocalN 으로,
+// 그 뒤 슬롯은 일반 OpGetLocal 로 컴파일되는지 테스트합니다.
+func TestGetLocalFastPath(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn(a, b) { a; b }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn(a, b, c, d) { d }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 3),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestCompileThunk는 표현식이 OpReturnValue 로 끝나는 인자 없는 함수로 컴파일되는지 테스트합니다.
+func TestCompileThunk(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("let x = 1;"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expr := parse("x + 2").Statements[0].(*ast.ExpressionStatement).Expression
+	fn, err := compiler.CompileThunk(expr)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expectedInstructions := []code.Instructions{
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpReturnValue),
+	}
+	err = testInstructions(expectedInstructions, fn.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	if fn.NumParameters != 0 {
+		t.Errorf("NumParameters wrong. want=0, got=%d", fn.NumParameters)
+	}
+
+	// 썽크는 바깥 스코프의 명령어를 건드리지 않습니다.
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpSetGlobal, 0),
+	}, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	// 에러가 나면 스코프가 원래대로 돌아옵니다.
+	_, err = compiler.CompileThunk(&ast.Identifier{Value: "missing"})
+	if err == nil {
+		t.Fatalf("expected error for undefined variable, got none")
+	}
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. want=0, got=%d", compiler.scopeIndex)
+	}
+}
+
+// TestGlobalCount는 바이트코드가 사용하는 전역 슬롯 수를 올바르게 세는지 테스트합니다.
+func TestGlobalCount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"1 + 2", 0},
+		{"let a = 1; let b = 2; a + b", 2},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		count := compiler.Bytecode().GlobalCount()
+		if count != tt.expected {
+			t.Errorf("GlobalCount wrong for %q. want=%d, got=%d", tt.input, tt.expected, count)
+		}
+	}
+
+	// REPL 처럼 이전 입력에서 정의된 전역을 함수 본문에서만 읽는 경우도 셉니다.
+	symbolTable := newGlobalSymbolTable()
+	symbolTable.Define("a")
+	symbolTable.Define("b")
+
+	compiler := NewWithState(symbolTable, []object.Object{})
+	err := compiler.Compile(parse("fn() { b }"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	count := compiler.Bytecode().GlobalCount()
+	if count != 2 {
+		t.Errorf("GlobalCount wrong for global read inside function. want=2, got=%d", count)
+	}
+}
+
+// TestNestedFunctionConstants는 중첩된 함수 리터럴이 공유 상수 풀에 서로 다른 인덱스로 들어가고,
+// 각 함수를 푸시하는 명령어가 올바른 인덱스를 가리키는지 테스트합니다.
+// 안쪽 함수는 바깥 함수의 컴파일이 끝나기 전에 상수 풀에 추가되므로 항상 더 작은 인덱스를 받습니다.
+// 이 컴파일러는 클로저를 만들지 않으므로 OpClosure 대신 OpConstant 로 함수를 푸시합니다.
+func TestNestedFunctionConstants(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `let outer = fn() { let inner = fn() { 1 }; inner() }; outer()`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1), // inner
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2), // outer
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 나란히 있는 안쪽 함수들 사이에 다른 상수가 끼어도 인덱스가 섞이지 않습니다.
+			input: `fn() { let f = fn() { 1 }; let g = fn() { 2 }; f() + g() }`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1), // f
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 3), // g
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpGetLocal1),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+// TestFunctionCompileErrorRestoresScope는 함수 본문 컴파일이 실패해도 컴파일러가
+// 최상위 스코프와 전역 심볼 테이블로 되돌아오는지 테스트합니다.
+func TestFunctionCompileErrorRestoresScope(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("fn() { missing }"))
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. want=0, got=%d", compiler.scopeIndex)
+	}
+	if compiler.symbolTable.Outer != nil {
+		t.Errorf("symbol table was not restored to the global table")
+	}
+}
+
+// TestEmitEncodingError는 인코딩할 수 없는 명령어가 빈 명령어로 스트림에 섞이지 않고
+// Compile 의 에러로 드러나는지 테스트합니다.
+func TestEmitEncodingError(t *testing.T) {
+	compiler := New()
+	compiler.emit(code.OpConstant, 70000)
+
+	if len(compiler.currentInstructions()) != 0 {
+		t.Errorf("instruction was emitted. got=%q", compiler.currentInstructions())
+	}
+
+	err := compiler.Compile(parse("1"))
+	if err == nil {
+		t.Fatalf("expected compiler error but resulted in none.")
+	}
+
+	expected := "cannot encode OpConstant: operand 70000 overflows 2-byte width"
+	if err.Error() != expected {
+		t.Errorf("wrong compiler error. want=%q, got=%q", expected, err.Error())
+	}
+
+	// Reset 하면 에러가 지워집니다.
+	compiler.Reset(false)
+	err = compiler.Compile(parse("1"))
+	if err != nil {
+		t.Fatalf("compiler error after Reset: %s", err)
+	}
+}
+
+// TestOperandLimits는 피연산자 폭을 넘는 지역 바인딩, 배열/해시 원소 수, 점프 위치가
+// 잘못된 명령어 대신 컴파일 에러가 되는지 테스트합니다.
+func TestOperandLimits(t *testing.T) {
+	// 렉서는 식별자에 숫자를 허용하지 않으므로 글자로만 된 이름(xaa, xab, ...)을 만듭니다.
+	// 앞의 x 는 if, fn 같은 키워드와 겹치지 않게 합니다.
+	var lets strings.Builder
+	for i := 0; i <= 256; i++ {
+		fmt.Fprintf(&lets, "let x%c%c = 0; ", 'a'+i/26, 'a'+i%26)
+	}
+
+	params := []*ast.Identifier{}
+	for i := 0; i <= 256; i++ {
+		params = append(params, &ast.Identifier{Value: fmt.Sprintf("p%d", i)})
+	}
+
+	elements := []ast.Expression{}
+	for i := 0; i <= math.MaxUint16; i++ {
+		elements = append(elements, &ast.IntegerLiteral{Value: 1})
+	}
+
+	pairs := map[ast.Expression]ast.Expression{}
+	for i := 0; i <= math.MaxUint16/2; i++ {
+		pairs[&ast.IntegerLiteral{Value: int64(i)}] = &ast.IntegerLiteral{Value: 1}
+	}
+
+	tests := []struct {
+		name     string
+		node     ast.Node
+		expected string
+	}{
+		{
+			"locals",
+			parse("fn() { " + lets.String() + "}"),
+			"too many local bindings (max 256)",
+		},
+		{
+			"parameters",
+			&ast.FunctionLiteral{Parameters: params, Body: &ast.BlockStatement{}},
+			"too many local bindings (max 256)",
+		},
+		{
+			"array",
+			&ast.ArrayLiteral{Elements: elements},
+			"too many elements in array literal (max 65535)",
+		},
+		{
+			"hash",
+			&ast.HashLiteral{Pairs: pairs},
+			"too many pairs in hash literal (max 32767)",
+		},
+		{
+			// OpConstant 와 OpPop 한 쌍이 4바이트이므로 결과 블록이 2바이트 점프 범위를 넘습니다.
+			"jump",
+			parse("if (true) { " + strings.Repeat("1; ", 16400) + "}"),
+			"jump target 65606 out of range (max 65535)",
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(tt.node)
+		if err == nil {
+			t.Errorf("%s: expected compiler error but resulted in none.", tt.name)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("%s: wrong compiler error. want=%q, got=%q", tt.name, tt.expected, err.Error())
+		}
+	}
+}
+
+// TestLetSelfReference는 함수가 아닌 값에서 바인딩 자신을 참조하면, 값이 없는 슬롯을 읽는 대신
+// 정의되지 않은 변수 에러가 되는지 테스트합니다.
+func TestLetSelfReference(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = x;", "undefined variable x"},
+		{"fn() { let a = a; }", "undefined variable a"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected compiler error for %q but resulted in none.", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	// 바깥의 같은 이름을 가리는 경우에는 값이 바깥 바인딩을 읽습니다.
+	runCompilerTests(t, []compilerTestCase{
+		{
+			input: "let a = 1; fn() { let a = a; a }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	})
+}
+
+// TestEmitTracksLastInstruction는 emit이 마지막 명령어와 그 이전 명령어를 올바르게 기록하는지 테스트합니다.
+func TestEmitTracksLastInstruction(t *testing.T) {
+	compiler := New()
+
+	compiler.emit(code.OpConstant, 0)
+	if compiler.scopes[compiler.scopeIndex].lastInstruction.Opcode != code.OpConstant {
+		t.Errorf("lastInstruction.Opcode wrong. got=%d, want=%d",
+			compiler.scopes[compiler.scopeIndex].lastInstruction.Opcode, code.OpConstant)
+	}
+	if compiler.scopes[compiler.scopeIndex].lastInstruction.Position != 0 {
+		t.Errorf("lastInstruction.Position wrong. got=%d, want=%d",
+			compiler.scopes[compiler.scopeIndex].lastInstruction.Position, 0)
+	}
+
+	compiler.emit(code.OpAdd)
+	if compiler.scopes[compiler.scopeIndex].lastInstruction.Opcode != code.OpAdd {
+		t.Errorf("lastInstruction.Opcode wrong. got=%d, want=%d",
+			compiler.scopes[compiler.scopeIndex].lastInstruction.Opcode, code.OpAdd)
+	}
+	// OpConstant 는 3바이트이므로 OpAdd 는 위치 3에서 시작합니다.
+	if compiler.scopes[compiler.scopeIndex].lastInstruction.Position != 3 {
+		t.Errorf("lastInstruction.Position wrong. got=%d, want=%d",
+			compiler.scopes[compiler.scopeIndex].lastInstruction.Position, 3)
+	}
+
+	previous := compiler.scopes[compiler.scopeIndex].previousInstruction
+	if previous.Opcode != code.OpConstant {
+		t.Errorf("previousInstruction.Opcode wrong. got=%d, want=%d",
+			previous.Opcode, code.OpConstant)
+	}
+	if previous.Position != 0 {
+		t.Errorf("previousInstruction.Position wrong. got=%d, want=%d",
+			previous.Position, 0)
+	}
+}
+
+// TestChangeOperand는 점프 명령어의 임시 피연산자가 실제 오프셋으로 수정되는지 테스트합니다.
+func TestChangeOperand(t *testing.T) {
+	compiler := New()
+
+	compiler.emit(code.OpTrue)
+	jumpPos := compiler.emit(code.OpJumpNotTruthy, 9999)
+	compiler.emit(code.OpConstant, 0)
+
+	compiler.changeOperand(jumpPos, 7)
+
+	expected := concatInstructions([]code.Instructions{
+		code.Make(code.OpTrue),
+		code.Make(code.OpJumpNotTruthy, 7),
+		code.Make(code.OpConstant, 0),
+	})
+
+	err := testInstructions([]code.Instructions{expected}, compiler.currentInstructions())
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+// TestRemoveLastPop는 마지막 OpPop 이 제거되고 lastInstruction 이 복원되는지 테스트합니다.
+func TestRemoveLastPop(t *testing.T) {
+	compiler := New()
+
+	compiler.emit(code.OpConstant, 0)
+	compiler.emit(code.OpPop)
+
+	if !compiler.lastInstructionIs(code.OpPop) {
+		t.Fatalf("lastInstructionIs(OpPop) returned false after emitting OpPop")
+	}
+
+	compiler.removeLastPop()
+
+	if compiler.lastInstructionIs(code.OpPop) {
+		t.Errorf("lastInstructionIs(OpPop) returned true after removeLastPop")
+	}
+	if compiler.scopes[compiler.scopeIndex].lastInstruction.Opcode != code.OpConstant {
+		t.Errorf("lastInstruction.Opcode wrong. got=%d, want=%d",
+			compiler.scopes[compiler.scopeIndex].lastInstruction.Opcode, code.OpConstant)
+	}
+	if len(compiler.currentInstructions()) != 3 {
+		t.Errorf("instructions length wrong. got=%d, want=%d",
+			len(compiler.currentInstructions()), 3)
+	}
+}
+
+// TestCompilerScopes는 스코프에 들어가고 나올 때 명령어 스트림이 스코프별로 분리되는지 테스트합니다.
+func TestCompilerScopes(t *testing.T) {
+	compiler := New()
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. got=%d, want=%d", compiler.scopeIndex, 0)
+	}
+	globalSymbolTable := compiler.symbolTable
+
+	compiler.emit(code.OpMul)
+
+	compiler.enterScope()
+	if compiler.scopeIndex != 1 {
+		t.Errorf("scopeIndex wrong. got=%d, want=%d", compiler.scopeIndex, 1)
+	}
+	if compiler.symbolTable.Outer != globalSymbolTable {
+		t.Errorf("compiler did not enclose symbolTable")
+	}
+
+	compiler.emit(code.OpSub)
+
+	if len(compiler.scopes[compiler.scopeIndex].instructions) != 1 {
+		t.Errorf("instructions length wrong. got=%d",
+			len(compiler.scopes[compiler.scopeIndex].instructions))
+	}
+
+	last := compiler.scopes[compiler.scopeIndex].lastInstruction
+	if last.Opcode != code.OpSub {
+		t.Errorf("lastInstruction.Opcode wrong. got=%d, want=%d",
+			last.Opcode, code.OpSub)
+	}
+
+	compiler.leaveScope()
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. got=%d, want=%d", compiler.scopeIndex, 0)
+	}
+	if compiler.symbolTable != globalSymbolTable {
+		t.Errorf("compiler did not restore global symbol table")
+	}
+	if compiler.symbolTable.Outer != nil {
+		t.Errorf("compiler modified global symbol table incorrectly")
+	}
+
+	compiler.emit(code.OpAdd)
+
+	if len(compiler.scopes[compiler.scopeIndex].instructions) != 2 {
+		t.Errorf("instructions length wrong. got=%d",
+			len(compiler.scopes[compiler.scopeIndex].instructions))
+	}
+
+	last = compiler.scopes[compiler.scopeIndex].lastInstruction
+	if last.Opcode != code.OpAdd {
+		t.Errorf("lastInstruction.Opcode wrong. got=%d, want=%d",
+			last.Opcode, code.OpAdd)
+	}
+
+	previous := compiler.scopes[compiler.scopeIndex].previousInstruction
+	if previous.Opcode != code.OpMul {
+		t.Errorf("previousInstruction.Opcode wrong. got=%d, want=%d",
+			previous.Opcode, code.OpMul)
+	}
+}
+
+// TestEmitRaw는 EmitRaw 로 덧붙인 명령어가 lastInstruction 과 previousInstruction 에 반영되고,
+// 잘린 명령어는 거부되는지 테스트합니다.
+func TestEmitRaw(t *testing.T) {
+	compiler := New()
+	compiler.emit(code.OpTrue)
+
+	raw := concatInstructions([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpPop),
+	})
+	err := compiler.EmitRaw(raw)
+	if err != nil {
+		t.Fatalf("EmitRaw failed: %s", err)
+	}
+
+	last := compiler.scopes[compiler.scopeIndex].lastInstruction
+	if last.Opcode != code.OpPop || last.Position != 4 {
+		t.Errorf("wrong lastInstruction. got=%+v", last)
+	}
+
+	previous := compiler.scopes[compiler.scopeIndex].previousInstruction
+	if previous.Opcode != code.OpConstant || previous.Position != 1 {
+		t.Errorf("wrong previousInstruction. got=%+v", previous)
+	}
+
+	// 덧붙인 OpPop 도 평소처럼 제거할 수 있어야 합니다.
+	compiler.removeLastPop()
+	compiler.emit(code.OpMinus)
+
+	expected := []code.Instructions{
+		code.Make(code.OpTrue),
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpMinus),
+	}
+
+	err = testInstructions(expected, compiler.currentInstructions())
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	// 잘린 명령어는 거부되고 스트림은 그대로 남습니다.
+	err = compiler.EmitRaw(code.Instructions{byte(code.OpConstant), 0})
+	if err == nil {
+		t.Fatalf("expected error for truncated instruction, got none")
+	}
+
+	err = testInstructions(expected, compiler.currentInstructions())
+	if err != nil {
+		t.Fatalf("instructions changed after failed EmitRaw: %s", err)
+	}
+}
+
+// BenchmarkCompileLargeFunction은 긴 함수 본문을 컴파일할 때, 명령어 슬라이스를 본문 길이로 미리
+// 할당하는 enterScopeWithHint(hint)와 enterScope(no-hint)를 비교합니다. 힌트는 할당 횟수보다
+// 재할당 때 할당되는 바이트 수를 줄이므로 B/op 를 비교합니다.
+func BenchmarkCompileLargeFunction(b *testing.B) {
+	var src strings.Builder
+	src.WriteString("fn() {\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&src, "%d * 2;\n", i)
+	}
+	src.WriteString("}")
+	program := parse(src.String())
+	body := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral).Body
+
+	for _, bm := range []struct {
+		name       string
+		enterScope func(c *Compiler)
+	}{
+		{"hint", func(c *Compiler) {
+			c.enterScopeWithHint(len(body.Statements) * instructionBytesPerStatement)
+		}},
+		{"no-hint", func(c *Compiler) { c.enterScope() }},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				compiler := New()
+				bm.enterScope(compiler)
+				err := compiler.Compile(body)
+				if err != nil {
+					b.Fatalf("compiler error: %s", err)
+				}
+				compiler.leaveScope()
+			}
+		})
+	}
+}
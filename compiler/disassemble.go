@@ -0,0 +1,120 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/code"
+	"monkey/object"
+	"os"
+	"strings"
+)
+
+// String 메서드는 바이트코드를 사람이 읽을 수 있는 형태로 역어셈블합니다.
+// 명령어 목록 뒤에 상수 풀을 번호와 함께 나열하며,
+// 컴파일된 함수 상수는 그 안의 명령어까지 들여쓰기하여 보여 줍니다.
+func (b *Bytecode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("Instructions:\n")
+	out.WriteString(b.Instructions.String())
+
+	out.WriteString("\nConstants:\n")
+	for i, c := range b.Constants {
+		switch c := c.(type) {
+		case *object.CompiledFunction:
+			fmt.Fprintf(&out, "%04d %s locals=%d parameters=%d\n",
+				i, c.Type(), c.NumLocals, c.NumParameters)
+			out.WriteString(indent(c.Instructions.String(), "    "))
+		default:
+			fmt.Fprintf(&out, "%04d %s %s\n", i, c.Type(), c.Inspect())
+		}
+	}
+
+	return out.String()
+}
+
+// DisassembleFile 함수는 Serialize 로 저장된 바이트코드 파일을 읽어 역어셈블한 결과를 반환합니다.
+// 출력 형식은 Bytecode.String 과 같습니다.
+func DisassembleFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	bytecode, err := DeserializeBytecode(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", path, err)
+	}
+
+	return bytecode.String(), nil
+}
+
+// DiffInstructions 함수는 두 명령어 스트림을 역어셈블해 줄 단위로 비교한 결과를 반환합니다.
+// 같은 줄은 "  ", want 에만 있는 줄은 "- ", got 에만 있는 줄은 "+ " 로 시작하며,
+// 첫 번째로 달라진 명령어 앞에는 그 오프셋을 알리는 줄이 붙습니다. 두 스트림이 같으면 빈 문자열입니다.
+func DiffInstructions(want, got code.Instructions) string {
+	wantLines := strings.Split(strings.TrimSuffix(want.String(), "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(got.String(), "\n"), "\n")
+
+	var out bytes.Buffer
+	foundFirst := false
+
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+
+		if w == g {
+			fmt.Fprintf(&out, "  %s\n", w)
+			continue
+		}
+
+		if !foundFirst {
+			foundFirst = true
+			fmt.Fprintf(&out, "first difference at %s\n", diffOffset(w, g))
+		}
+		if w != "" {
+			fmt.Fprintf(&out, "- %s\n", w)
+		}
+		if g != "" {
+			fmt.Fprintf(&out, "+ %s\n", g)
+		}
+	}
+
+	if !foundFirst {
+		return ""
+	}
+	return out.String()
+}
+
+// diffOffset 함수는 역어셈블된 줄 앞의 오프셋을 꺼냅니다. 한쪽 줄이 없으면 다른 쪽을 사용합니다.
+func diffOffset(want, got string) string {
+	line := want
+	if line == "" {
+		line = got
+	}
+	if offset, _, ok := strings.Cut(line, " "); ok {
+		return offset
+	}
+	return line
+}
+
+// indent 함수는 s 의 각 줄 앞에 prefix 를 붙입니다.
+func indent(s string, prefix string) string {
+	var out bytes.Buffer
+
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line == "" {
+			continue
+		}
+		out.WriteString(prefix)
+		out.WriteString(line)
+	}
+
+	return out.String()
+}
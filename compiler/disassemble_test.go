@@ -0,0 +1,146 @@
+package compiler
+
+import (
+	"monkey/code"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBytecodeString(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse(`let f = fn(a) { a + 1 }; f("x");`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := `Instructions:
+0000 OpConstant 1
+0003 OpSetGlobal 0
+0006 OpGetGlobal 0
+0009 OpConstant 2
+0012 OpCall 1
+0014 OpPop
+
+Constants:
+0000 INTEGER 1
+0001 COMPILED_FUNCTION_OBJ locals=1 parameters=1
+    0000 OpGetLocal0
+    0001 OpConstant 0
+    0004 OpAdd
+    0005 OpReturnValue
+0002 STRING x
+`
+
+	actual := compiler.Bytecode().String()
+	if actual != expected {
+		t.Errorf("bytecode.String() wrong.\nwant=%q\ngot =%q", expected, actual)
+	}
+}
+
+func TestDisassembleFile(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse(`let f = fn(a) { a + 1 }; f(2);`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "program.mnky")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("os.Create failed: %s", err)
+	}
+	err = compiler.Bytecode().Serialize(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Serialize failed: %s", err)
+	}
+
+	actual, err := DisassembleFile(path)
+	if err != nil {
+		t.Fatalf("DisassembleFile failed: %s", err)
+	}
+
+	for _, want := range []string{
+		"0000 OpConstant 1",
+		"0003 OpSetGlobal 0",
+		"OpCall 1",
+		"COMPILED_FUNCTION_OBJ locals=1 parameters=1",
+		"    0005 OpReturnValue",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("disassembly does not contain %q.\ngot=%s", want, actual)
+		}
+	}
+}
+
+func TestDisassembleFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "garbage")
+	err := os.WriteFile(path, []byte("not bytecode"), 0o644)
+	if err != nil {
+		t.Fatalf("os.WriteFile failed: %s", err)
+	}
+
+	_, err = DisassembleFile(path)
+	if err == nil {
+		t.Fatalf("expected error for invalid file, got none")
+	}
+
+	_, err = DisassembleFile(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatalf("expected error for missing file, got none")
+	}
+}
+
+func TestDiffInstructions(t *testing.T) {
+	want := concatInstructions([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	})
+	got := concatInstructions([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpSub),
+		code.Make(code.OpPop),
+	})
+
+	expected := `  0000 OpConstant 0
+  0003 OpConstant 1
+first difference at 0006
+- 0006 OpAdd
++ 0006 OpSub
+  0007 OpPop
+`
+
+	actual := DiffInstructions(want, got)
+	if actual != expected {
+		t.Errorf("wrong diff.\nwant=%q\ngot =%q", expected, actual)
+	}
+
+	if diff := DiffInstructions(want, want); diff != "" {
+		t.Errorf("expected empty diff for identical streams, got=%q", diff)
+	}
+}
+
+func TestDiffInstructionsDifferentLength(t *testing.T) {
+	want := concatInstructions([]code.Instructions{
+		code.Make(code.OpTrue),
+		code.Make(code.OpPop),
+	})
+	got := concatInstructions([]code.Instructions{
+		code.Make(code.OpTrue),
+	})
+
+	expected := `  0000 OpTrue
+first difference at 0001
+- 0001 OpPop
+`
+
+	actual := DiffInstructions(want, got)
+	if actual != expected {
+		t.Errorf("wrong diff.\nwant=%q\ngot =%q", expected, actual)
+	}
+}
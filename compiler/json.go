@@ -0,0 +1,90 @@
+package compiler
+
+import (
+	"encoding/json"
+	"monkey/code"
+	"monkey/object"
+)
+
+// jsonInstruction 은 역어셈블된 명령어 하나의 JSON 표현입니다.
+type jsonInstruction struct {
+	Offset   int    `json:"offset"`
+	Opcode   string `json:"opcode"`
+	Operands []int  `json:"operands"`
+}
+
+// jsonConstant 는 상수 풀 항목 하나의 JSON 표현입니다.
+// 컴파일된 함수는 value 대신 지역 변수/매개변수 수와 명령어 목록을 가집니다.
+type jsonConstant struct {
+	Type          string            `json:"type"`
+	Value         interface{}       `json:"value,omitempty"`
+	NumLocals     *int              `json:"numLocals,omitempty"`
+	NumParameters *int              `json:"numParameters,omitempty"`
+	Instructions  []jsonInstruction `json:"instructions,omitempty"`
+}
+
+type jsonBytecode struct {
+	Instructions []jsonInstruction `json:"instructions"`
+	Constants    []jsonConstant    `json:"constants"`
+}
+
+// MarshalJSON 메서드는 바이트코드를 외부 도구가 읽을 수 있는 JSON 으로 변환합니다.
+// 필드 순서가 구조체로 고정되어 있으므로 같은 바이트코드는 항상 같은 출력을 만듭니다.
+func (b *Bytecode) MarshalJSON() ([]byte, error) {
+	instructions, err := instructionsToJSON(b.Instructions)
+	if err != nil {
+		return nil, err
+	}
+
+	constants := []jsonConstant{}
+	for _, c := range b.Constants {
+		jc, err := constantToJSON(c)
+		if err != nil {
+			return nil, err
+		}
+		constants = append(constants, jc)
+	}
+
+	return json.Marshal(jsonBytecode{
+		Instructions: instructions,
+		Constants:    constants,
+	})
+}
+
+func instructionsToJSON(ins code.Instructions) ([]jsonInstruction, error) {
+	out := []jsonInstruction{}
+
+	err := ins.Iterate(func(offset int, def *code.Definition, operands []int) error {
+		out = append(out, jsonInstruction{
+			Offset:   offset,
+			Opcode:   def.Name,
+			Operands: operands,
+		})
+		return nil
+	})
+
+	return out, err
+}
+
+func constantToJSON(obj object.Object) (jsonConstant, error) {
+	jc := jsonConstant{Type: string(obj.Type())}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		jc.Value = obj.Value
+	case *object.String:
+		jc.Value = obj.Value
+	case *object.CompiledFunction:
+		instructions, err := instructionsToJSON(obj.Instructions)
+		if err != nil {
+			return jc, err
+		}
+		jc.NumLocals = &obj.NumLocals
+		jc.NumParameters = &obj.NumParameters
+		jc.Instructions = instructions
+	default:
+		jc.Value = obj.Inspect()
+	}
+
+	return jc, nil
+}
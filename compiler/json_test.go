@@ -0,0 +1,51 @@
+package compiler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBytecodeMarshalJSON(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse(`let f = fn(a) { a + 1 }; f("x");`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := `{"instructions":[` +
+		`{"offset":0,"opcode":"OpConstant","operands":[1]},` +
+		`{"offset":3,"opcode":"OpSetGlobal","operands":[0]},` +
+		`{"offset":6,"opcode":"OpGetGlobal","operands":[0]},` +
+		`{"offset":9,"opcode":"OpConstant","operands":[2]},` +
+		`{"offset":12,"opcode":"OpCall","operands":[1]},` +
+		`{"offset":14,"opcode":"OpPop","operands":[]}],` +
+		`"constants":[` +
+		`{"type":"INTEGER","value":1},` +
+		`{"type":"COMPILED_FUNCTION_OBJ","numLocals":1,"numParameters":1,"instructions":[` +
+		`{"offset":0,"opcode":"OpGetLocal0","operands":[]},` +
+		`{"offset":1,"opcode":"OpConstant","operands":[0]},` +
+		`{"offset":4,"opcode":"OpAdd","operands":[]},` +
+		`{"offset":5,"opcode":"OpReturnValue","operands":[]}]},` +
+		`{"type":"STRING","value":"x"}]}`
+
+	actual, err := json.Marshal(compiler.Bytecode())
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %s", err)
+	}
+
+	if string(actual) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot =%s", expected, actual)
+	}
+}
+
+func TestBytecodeMarshalJSONEmpty(t *testing.T) {
+	actual, err := json.Marshal(New().Bytecode())
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %s", err)
+	}
+
+	expected := `{"instructions":[],"constants":[]}`
+	if string(actual) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot =%s", expected, actual)
+	}
+}
@@ -0,0 +1,244 @@
+package compiler
+
+import (
+	"math"
+	"monkey/code"
+	"monkey/object"
+)
+
+// optimizedInstruction 은 최적화 중인 명령어 하나를 나타냅니다.
+// origin 은 원래 명령어 스트림에서의 시작 오프셋으로, 점프 대상을 다시 계산할 때 사용합니다.
+type optimizedInstruction struct {
+	op       code.Opcode
+	operands []int
+	origin   int
+}
+
+// optimize 함수는 컴파일이 끝난 명령어 스트림에 핍홀(peephole) 최적화를 적용합니다.
+//
+// 현재는 두 정수 상수에 대한 산술 연산(OpConstant a; OpConstant b; OpAdd/OpSub/OpMul/OpDiv)과
+// 두 문자열 상수의 연결(OpAdd)을 미리 계산해 하나의 OpConstant 로 접습니다. 계산 결과는 상수 풀에 새로 추가되므로,
+// 갱신된 상수 풀을 명령어와 함께 반환합니다.
+//
+// 두 정수 상수의 동등 비교(OpEqual/OpNotEqual)는 결과에 따라 OpTrue 또는 OpFalse 로 접습니다.
+// 또한 비교 뒤의 부정(OpEqual; OpBang 과 OpNotEqual; OpBang)은 반대 비교 하나로 합칩니다.
+//
+// 명령어를 줄이면 뒤따르는 오프셋이 바뀌므로 점프 피연산자도 새 오프셋으로 고칩니다.
+// 점프 대상이 되는 명령어는 다른 경로에서도 실행되므로 접지 않습니다.
+func optimize(ins code.Instructions, constants []object.Object) (code.Instructions, []object.Object) {
+	decoded := []optimizedInstruction{}
+	jumpTargets := map[int]bool{}
+
+	err := ins.Iterate(func(offset int, def *code.Definition, operands []int) error {
+		op := code.Opcode(ins[offset])
+		if isJump(op) {
+			jumpTargets[operands[0]] = true
+		}
+		decoded = append(decoded, optimizedInstruction{op: op, operands: operands, origin: offset})
+		return nil
+	})
+	if err != nil {
+		// 해석할 수 없는 명령어 스트림은 건드리지 않습니다.
+		return ins, constants
+	}
+
+	out := []optimizedInstruction{}
+	for _, cur := range decoded {
+		out = append(out, cur)
+
+		if fused, ok := fuseNegation(out, jumpTargets); ok {
+			out = fused
+			continue
+		}
+
+		n := len(out)
+		if n < 3 {
+			continue
+		}
+
+		left, right, operator := out[n-3], out[n-2], out[n-1]
+		if jumpTargets[right.origin] || jumpTargets[operator.origin] {
+			continue
+		}
+
+		if op, ok := foldComparison(left, right, operator, constants); ok {
+			folded := optimizedInstruction{op: op, operands: []int{}, origin: left.origin}
+			out = append(out[:n-3], folded)
+			continue
+		}
+
+		result, ok := foldConstants(left, right, operator, constants)
+		if !ok {
+			continue
+		}
+
+		constants = append(constants, result)
+		index := len(constants) - 1
+
+		folded := optimizedInstruction{op: code.OpConstant, operands: []int{index}, origin: left.origin}
+		if index > math.MaxUint16 {
+			folded.op = code.OpConstantWide
+		}
+
+		out = append(out[:n-3], folded)
+	}
+
+	return encodeOptimized(out, len(ins)), constants
+}
+
+// fuseNegation 함수는 out 의 마지막 두 명령어가 비교와 그 결과의 부정이면, 둘을 반대 비교 하나로 바꿉니다.
+// OpGreaterThan 의 부정은 피연산자 순서를 바꿔야 하는 <= 이므로 합치지 않습니다.
+func fuseNegation(out []optimizedInstruction, jumpTargets map[int]bool) ([]optimizedInstruction, bool) {
+	n := len(out)
+	if n < 2 {
+		return out, false
+	}
+
+	comparison, bang := out[n-2], out[n-1]
+	if bang.op != code.OpBang || jumpTargets[bang.origin] {
+		return out, false
+	}
+
+	switch comparison.op {
+	case code.OpEqual:
+		comparison.op = code.OpNotEqual
+	case code.OpNotEqual:
+		comparison.op = code.OpEqual
+	default:
+		return out, false
+	}
+
+	return append(out[:n-2], comparison), true
+}
+
+// constantOperands 함수는 두 명령어가 모두 상수를 푸시하면 그 상수들을 반환합니다.
+// EmitRaw 로 직접 넣은 명령어처럼 인덱스가 상수 풀 밖을 가리키면 접지 않도록 false 를 반환합니다.
+func constantOperands(
+	left, right optimizedInstruction,
+	constants []object.Object,
+) (object.Object, object.Object, bool) {
+	if !isConstant(left.op) || !isConstant(right.op) {
+		return nil, nil, false
+	}
+
+	l, r := left.operands[0], right.operands[0]
+	if l >= len(constants) || r >= len(constants) {
+		return nil, nil, false
+	}
+
+	return constants[l], constants[r], true
+}
+
+// foldConstants 함수는 두 상수와 연산자로 이루어진 명령어 묶음을 미리 계산할 수 있으면
+// 그 결과 객체를 반환합니다.
+func foldConstants(
+	left, right, operator optimizedInstruction,
+	constants []object.Object,
+) (object.Object, bool) {
+	leftObj, rightObj, ok := constantOperands(left, right, constants)
+	if !ok {
+		return nil, false
+	}
+
+	// 두 문자열 상수의 덧셈은 연결이므로 미리 이어 붙일 수 있습니다.
+	leftStr, leftIsStr := leftObj.(*object.String)
+	rightStr, rightIsStr := rightObj.(*object.String)
+	if leftIsStr && rightIsStr {
+		if operator.op != code.OpAdd {
+			return nil, false
+		}
+		return &object.String{Value: leftStr.Value + rightStr.Value}, true
+	}
+
+	leftInt, ok := leftObj.(*object.Integer)
+	if !ok {
+		return nil, false
+	}
+	rightInt, ok := rightObj.(*object.Integer)
+	if !ok {
+		return nil, false
+	}
+
+	switch operator.op {
+	case code.OpAdd:
+		return &object.Integer{Value: leftInt.Value + rightInt.Value}, true
+	case code.OpSub:
+		return &object.Integer{Value: leftInt.Value - rightInt.Value}, true
+	case code.OpMul:
+		return &object.Integer{Value: leftInt.Value * rightInt.Value}, true
+	case code.OpDiv:
+		// 0 으로 나누는 경우는 실행 시점의 에러로 남겨 둡니다.
+		if rightInt.Value == 0 {
+			return nil, false
+		}
+		return &object.Integer{Value: leftInt.Value / rightInt.Value}, true
+	}
+
+	return nil, false
+}
+
+// foldComparison 함수는 두 정수 상수의 동등 비교를 미리 계산할 수 있으면
+// 결과에 해당하는 OpTrue 또는 OpFalse 를 반환합니다.
+func foldComparison(
+	left, right, operator optimizedInstruction,
+	constants []object.Object,
+) (code.Opcode, bool) {
+	if operator.op != code.OpEqual && operator.op != code.OpNotEqual {
+		return 0, false
+	}
+	leftObj, rightObj, ok := constantOperands(left, right, constants)
+	if !ok {
+		return 0, false
+	}
+
+	leftInt, ok := leftObj.(*object.Integer)
+	if !ok {
+		return 0, false
+	}
+	rightInt, ok := rightObj.(*object.Integer)
+	if !ok {
+		return 0, false
+	}
+
+	result := leftInt.Value == rightInt.Value
+	if operator.op == code.OpNotEqual {
+		result = !result
+	}
+
+	if result {
+		return code.OpTrue, true
+	}
+	return code.OpFalse, true
+}
+
+// encodeOptimized 함수는 최적화된 명령어들을 다시 바이트로 인코딩하고,
+// 점프 피연산자를 새 오프셋으로 고칩니다. origLen 은 원래 스트림의 길이로,
+// 스트림 끝을 가리키는 점프를 처리하는 데 사용합니다.
+func encodeOptimized(out []optimizedInstruction, origLen int) code.Instructions {
+	newOffsets := map[int]int{}
+	offset := 0
+	for _, in := range out {
+		newOffsets[in.origin] = offset
+		offset += len(code.Make(in.op, in.operands...))
+	}
+	newOffsets[origLen] = offset
+
+	result := code.Instructions{}
+	for _, in := range out {
+		operands := in.operands
+		if isJump(in.op) {
+			operands = []int{newOffsets[operands[0]]}
+		}
+		result = append(result, code.Make(in.op, operands...)...)
+	}
+
+	return result
+}
+
+func isJump(op code.Opcode) bool {
+	return op == code.OpJump || op == code.OpJumpNotTruthy
+}
+
+func isConstant(op code.Opcode) bool {
+	return op == code.OpConstant || op == code.OpConstantWide
+}
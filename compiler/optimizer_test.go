@@ -0,0 +1,363 @@
+package compiler
+
+import (
+	"monkey/code"
+	"monkey/object"
+	"testing"
+)
+
+func runOptimizedCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		compiler.Optimize = true
+
+		err := compiler.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := compiler.Bytecode()
+
+		err = testInstructions(tt.expectedInstructions, bytecode.Instructions)
+		if err != nil {
+			t.Fatalf("testInstructions failed for %q: %s", tt.input, err)
+		}
+
+		err = testConstants(t, tt.expectedConstants, bytecode.Constants)
+		if err != nil {
+			t.Fatalf("testConstants failed for %q: %s", tt.input, err)
+		}
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// 원래 상수 2, 3 은 상수 풀에 남고, 계산된 5 가 새로 추가됩니다.
+			input:             "2 + 3",
+			expectedConstants: []interface{}{2, 3, 5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "10 - 4; 3 * 4; 9 / 3",
+			expectedConstants: []interface{}{10, 4, 3, 4, 9, 3, 6, 12, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 6),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 7),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 8),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 접힌 결과가 다시 다음 연산의 피연산자가 됩니다.
+			input:             "1 + 2 + 3",
+			expectedConstants: []interface{}{1, 2, 3, 3, 6},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 0 으로 나누기는 실행 시점의 에러로 남겨 둡니다.
+			input:             "1 / 0",
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn() { 2 * 3 }",
+			expectedConstants: []interface{}{
+				2,
+				3,
+				6,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, tests)
+}
+
+func TestConstantFoldingRewritesJumps(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 1 + 2 }; 3",
+			expectedConstants: []interface{}{1, 2, 3, 3},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 3),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 2),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// then 분기는 else 의 상수 2 를 건너뛰어 '+ 3' 으로 점프하므로 접으면 안 됩니다.
+			input:             "(if (true) { 1 } else { 2 }) + 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 13),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, tests)
+}
+
+func TestOptimizeDisabledByDefault(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("2 + 3"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+func TestStringConstantFolding(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// 원래 상수 "a", "b" 는 상수 풀에 남고, 이어 붙인 "ab" 가 새로 추가됩니다.
+			input:             `"a" + "b"`,
+			expectedConstants: []interface{}{"a", "b", "ab"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"a" + "b" + "c"`,
+			expectedConstants: []interface{}{"a", "b", "c", "ab", "abc"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, tests)
+
+	// 최적화가 꺼져 있으면 접지 않습니다.
+	compiler := New()
+	err := compiler.Compile(parse(`"a" + "b"`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}
+	err = testInstructions(expected, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+func TestNegatedComparisonFusion(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let a = 1; !(a == 2)",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpNotEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "let a = 1; !(a != 2)",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// > 의 부정은 합치지 않습니다.
+			input:             "let a = 1; !(a > 2)",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterThan),
+				code.Make(code.OpBang),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, tests)
+
+	// 최적화가 꺼져 있으면 두 명령어가 그대로 남습니다.
+	compiler := New()
+	err := compiler.Compile(parse("let a = 1; !(a == 2)"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpSetGlobal, 0),
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpEqual),
+		code.Make(code.OpBang),
+		code.Make(code.OpPop),
+	}
+	err = testInstructions(expected, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+func TestComparisonFolding(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 == 1",
+			expectedConstants: []interface{}{1, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 == 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 != 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// 접힌 산술 결과끼리의 비교도 접힙니다.
+			input:             "1 + 1 == 2",
+			expectedConstants: []interface{}{1, 1, 2, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, tests)
+
+	// 최적화가 꺼져 있으면 접지 않습니다.
+	compiler := New()
+	err := compiler.Compile(parse("1 == 1"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpEqual),
+		code.Make(code.OpPop),
+	}
+	err = testInstructions(expected, compiler.Bytecode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+}
+
+// TestOptimizeOutOfRangeConstant는 상수 풀 밖을 가리키는 OpConstant 를 접으려다
+// 패닉하지 않고 그대로 두는지 테스트합니다.
+func TestOptimizeOutOfRangeConstant(t *testing.T) {
+	constants := []object.Object{&object.Integer{Value: 1}}
+
+	for _, op := range []code.Opcode{code.OpAdd, code.OpEqual} {
+		ins := append(code.Instructions{}, code.Make(code.OpConstant, 0)...)
+		ins = append(ins, code.Make(code.OpConstant, 5)...)
+		ins = append(ins, code.Make(op)...)
+
+		optimized, newConstants := optimize(ins, constants)
+		if string(optimized) != string(ins) {
+			t.Errorf("instructions changed.\nwant=%q\ngot =%q", ins, optimized)
+		}
+		if len(newConstants) != len(constants) {
+			t.Errorf("constants changed. want=%d, got=%d", len(constants), len(newConstants))
+		}
+	}
+}
+
+func TestOptimizeLeavesNonIntegersAlone(t *testing.T) {
+	ins := append(code.Instructions{}, code.Make(code.OpConstant, 0)...)
+	ins = append(ins, code.Make(code.OpConstant, 1)...)
+	ins = append(ins, code.Make(code.OpAdd)...)
+
+	constants := []object.Object{&object.String{Value: "a"}, &object.Integer{Value: 1}}
+
+	optimized, newConstants := optimize(ins, constants)
+	if string(optimized) != string(ins) {
+		t.Errorf("instructions changed.\nwant=%q\ngot =%q", ins, optimized)
+	}
+	if len(newConstants) != len(constants) {
+		t.Errorf("constants changed. want=%d, got=%d", len(constants), len(newConstants))
+	}
+}
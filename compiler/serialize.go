@@ -0,0 +1,212 @@
+package compiler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"monkey/code"
+	"monkey/object"
+)
+
+// 직렬화된 바이트코드 파일의 머리말입니다.
+const (
+	bytecodeMagic   = "MNKY"
+	bytecodeVersion = 1
+)
+
+// 상수 풀의 각 상수 앞에 붙는 객체 타입 태그입니다.
+const (
+	tagInteger byte = iota + 1
+	tagString
+	tagCompiledFunction
+)
+
+// Serialize 메서드는 바이트코드를 이진 형식으로 w 에 씁니다.
+//
+// 형식은 모두 Big-Endian 이며 다음 순서로 구성됩니다.
+//
+//	매직("MNKY") | 버전(1바이트) | 명령어 길이(uint32) | 명령어 바이트
+//	| 상수 개수(uint32) | 상수들 (각각 타입 태그 1바이트 + 값)
+func (b *Bytecode) Serialize(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString(bytecodeMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(bytecodeVersion); err != nil {
+		return err
+	}
+
+	if err := writeInstructions(bw, b.Instructions); err != nil {
+		return err
+	}
+
+	if err := binary.Write(bw, binary.BigEndian, uint32(len(b.Constants))); err != nil {
+		return err
+	}
+	for i, c := range b.Constants {
+		if err := writeConstant(bw, c); err != nil {
+			return fmt.Errorf("constant %d: %s", i, err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeInstructions(w io.Writer, ins code.Instructions) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(ins))); err != nil {
+		return err
+	}
+	_, err := w.Write(ins)
+	return err
+}
+
+func writeConstant(w *bufio.Writer, obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		if err := w.WriteByte(tagInteger); err != nil {
+			return err
+		}
+		return binary.Write(w, binary.BigEndian, obj.Value)
+
+	case *object.String:
+		if err := w.WriteByte(tagString); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(len(obj.Value))); err != nil {
+			return err
+		}
+		_, err := w.WriteString(obj.Value)
+		return err
+
+	case *object.CompiledFunction:
+		if err := w.WriteByte(tagCompiledFunction); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(obj.NumLocals)); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(obj.NumParameters)); err != nil {
+			return err
+		}
+		return writeInstructions(w, obj.Instructions)
+
+	default:
+		return fmt.Errorf("cannot serialize constant of type %s", obj.Type())
+	}
+}
+
+// DeserializeBytecode 함수는 Serialize 로 쓰인 이진 형식을 읽어 Bytecode 를 복원합니다.
+func DeserializeBytecode(r io.Reader) (*Bytecode, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(bytecodeMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != bytecodeMagic {
+		return nil, fmt.Errorf("invalid bytecode header %q", magic)
+	}
+
+	version, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != bytecodeVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d", version)
+	}
+
+	instructions, err := readInstructions(br)
+	if err != nil {
+		return nil, err
+	}
+
+	var count uint32
+	if err := binary.Read(br, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	// 개수는 신뢰할 수 없는 입력이므로 미리 할당하지 않고, 읽은 만큼만 늘립니다.
+	constants := []object.Object{}
+	for i := 0; i < int(count); i++ {
+		c, err := readConstant(br)
+		if err != nil {
+			return nil, fmt.Errorf("constant %d: %s", i, err)
+		}
+		constants = append(constants, c)
+	}
+
+	return newBytecode(instructions, constants, builtinTable(nil)), nil
+}
+
+func readInstructions(r io.Reader) (code.Instructions, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	return readBytes(r, length)
+}
+
+// readBytes 함수는 r 에서 정확히 length 바이트를 읽습니다. 길이는 손상된 파일에서 온 값일 수 있으므로,
+// 그 크기만큼 미리 할당하지 않고 실제로 읽은 만큼만 메모리를 사용합니다.
+// 입력이 length 보다 짧으면 io.ErrUnexpectedEOF 를 반환합니다.
+func readBytes(r io.Reader, length uint32) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != int(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf, nil
+}
+
+func readConstant(r *bufio.Reader) (object.Object, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagInteger:
+		var value int64
+		if err := binary.Read(r, binary.BigEndian, &value); err != nil {
+			return nil, err
+		}
+		return &object.Integer{Value: value}, nil
+
+	case tagString:
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		buf, err := readBytes(r, length)
+		if err != nil {
+			return nil, err
+		}
+		return &object.String{Value: string(buf)}, nil
+
+	case tagCompiledFunction:
+		var numLocals, numParameters uint32
+		if err := binary.Read(r, binary.BigEndian, &numLocals); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, binary.BigEndian, &numParameters); err != nil {
+			return nil, err
+		}
+		ins, err := readInstructions(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.CompiledFunction{
+			Instructions:  ins,
+			NumLocals:     int(numLocals),
+			NumParameters: int(numParameters),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown constant tag %d", tag)
+	}
+}
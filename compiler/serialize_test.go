@@ -0,0 +1,129 @@
+package compiler
+
+import (
+	"bytes"
+	"monkey/object"
+	"testing"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	input := `
+	let greeting = "hello";
+	let add = fn(a, b) {
+		let inner = fn(x) { x * 2 };
+		inner(a) + b
+	};
+	add(1, 2);
+	`
+
+	compiler := New()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := compiler.Bytecode()
+
+	var buf bytes.Buffer
+	err = original.Serialize(&buf)
+	if err != nil {
+		t.Fatalf("serialize error: %s", err)
+	}
+
+	restored, err := DeserializeBytecode(&buf)
+	if err != nil {
+		t.Fatalf("deserialize error: %s", err)
+	}
+
+	if !bytes.Equal(original.Instructions, restored.Instructions) {
+		t.Errorf("instructions differ.\nwant=%q\ngot =%q",
+			original.Instructions, restored.Instructions)
+	}
+
+	if restored.MaxStackDepth != original.MaxStackDepth {
+		t.Errorf("MaxStackDepth differs. want=%d, got=%d",
+			original.MaxStackDepth, restored.MaxStackDepth)
+	}
+
+	if len(original.Constants) != len(restored.Constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d",
+			len(original.Constants), len(restored.Constants))
+	}
+
+	for i, want := range original.Constants {
+		got := restored.Constants[i]
+		if want.Type() != got.Type() {
+			t.Errorf("constant %d type wrong. want=%s, got=%s", i, want.Type(), got.Type())
+			continue
+		}
+
+		switch want := want.(type) {
+		case *object.Integer:
+			if want.Value != got.(*object.Integer).Value {
+				t.Errorf("constant %d value wrong. want=%d, got=%d",
+					i, want.Value, got.(*object.Integer).Value)
+			}
+		case *object.String:
+			if want.Value != got.(*object.String).Value {
+				t.Errorf("constant %d value wrong. want=%q, got=%q",
+					i, want.Value, got.(*object.String).Value)
+			}
+		case *object.CompiledFunction:
+			fn := got.(*object.CompiledFunction)
+			if !bytes.Equal(want.Instructions, fn.Instructions) {
+				t.Errorf("constant %d instructions differ.\nwant=%q\ngot =%q",
+					i, want.Instructions, fn.Instructions)
+			}
+			if want.NumLocals != fn.NumLocals || want.NumParameters != fn.NumParameters {
+				t.Errorf("constant %d locals/parameters wrong. want=%d/%d, got=%d/%d",
+					i, want.NumLocals, want.NumParameters, fn.NumLocals, fn.NumParameters)
+			}
+		}
+	}
+}
+
+func TestDeserializeInvalidHeader(t *testing.T) {
+	_, err := DeserializeBytecode(bytes.NewReader([]byte("NOPE\x01")))
+	if err == nil {
+		t.Fatalf("expected error for invalid header, got none")
+	}
+}
+
+// TestDeserializeHugeCounts는 머리말의 길이나 개수가 터무니없이 커도, 그만큼 메모리를 할당하지 않고
+// 잘린 입력에 대한 에러를 반환하는지 테스트합니다.
+func TestDeserializeHugeCounts(t *testing.T) {
+	header := bytecodeMagic + "\x01"
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"instruction length", header + "\xff\xff\xff\xff", "unexpected EOF"},
+		{"constant count", header + "\x00\x00\x00\x00" + "\xff\xff\xff\xff", "constant 0: EOF"},
+		{
+			"string length",
+			header + "\x00\x00\x00\x00" + "\x00\x00\x00\x01" + "\x02\xff\xff\xff\xff",
+			"constant 0: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := DeserializeBytecode(bytes.NewReader([]byte(tt.input)))
+		if err == nil {
+			t.Errorf("%s: expected error for truncated input, got none", tt.name)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%s: wrong error. want=%q, got=%q", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestSerializeUnsupportedConstant(t *testing.T) {
+	bytecode := &Bytecode{Constants: []object.Object{&object.Boolean{Value: true}}}
+
+	var buf bytes.Buffer
+	err := bytecode.Serialize(&buf)
+	if err == nil {
+		t.Fatalf("expected error for unsupported constant, got none")
+	}
+}
@@ -0,0 +1,107 @@
+package compiler
+
+import (
+	"fmt"
+	"monkey/object"
+)
+
+// SymbolScope 는 심볼이 정의된 스코프(유효 범위)를 나타냅니다.
+type SymbolScope string
+
+const (
+	// GlobalScope 는 최상위에서 정의된 전역 바인딩의 스코프입니다.
+	GlobalScope SymbolScope = "GLOBAL"
+	// LocalScope 는 함수 본문 안에서 정의된 지역 바인딩의 스코프입니다.
+	LocalScope SymbolScope = "LOCAL"
+	// BuiltinScope 는 object.Builtins 나 RegisterBuiltin 으로 등록된 내장 함수의 스코프입니다.
+	BuiltinScope SymbolScope = "BUILTIN"
+	// FunctionScope 는 현재 컴파일 중인 함수 자신의 이름이 속한 스코프입니다.
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+// Symbol 은 식별자 하나에 대해 컴파일러가 알아야 하는 정보를 담습니다.
+type Symbol struct {
+	Name  string      // 식별자 이름
+	Scope SymbolScope // 심볼이 속한 스코프
+	Index int         // 스코프 안에서의 슬롯 번호
+}
+
+// SymbolTable 은 식별자 이름을 Symbol 에 연결합니다.
+type SymbolTable struct {
+	Outer *SymbolTable // 바깥 스코프의 심볼 테이블 (전역 테이블이면 nil)
+
+	store          map[string]Symbol
+	numDefinitions int // 지금까지 정의된 심볼의 개수 (다음 인덱스)
+
+	// hostBuiltins 는 Compiler.RegisterBuiltin 으로 등록된 호스트 함수들입니다. 전역 테이블에만 쌓이며,
+	// i 번째 함수의 내장 함수 인덱스는 len(object.Builtins)+i 입니다.
+	hostBuiltins []*object.Builtin
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	return &SymbolTable{store: s}
+}
+
+// NewEnclosedSymbolTable 함수는 outer 를 바깥 스코프로 가지는 새 심볼 테이블을 만듭니다.
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define 메서드는 새로운 심볼을 정의하고, 0부터 차례로 증가하는 인덱스를 부여합니다.
+// 바깥 스코프가 있는 테이블에서 정의된 심볼은 지역 스코프가 됩니다.
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// DefineUnique 메서드는 Define 과 같지만, 같은 이름이 현재 테이블에 이미 바인딩되어 있으면
+// 에러를 반환합니다. 바깥 스코프의 이름을 가리는(shadowing) 것은 허용하며,
+// 내장 함수나 현재 함수 자신의 이름처럼 let 으로 만든 바인딩이 아닌 심볼도 가릴 수 있습니다.
+func (s *SymbolTable) DefineUnique(name string) (Symbol, error) {
+	if existing, ok := s.store[name]; ok {
+		if existing.Scope == GlobalScope || existing.Scope == LocalScope {
+			return Symbol{}, fmt.Errorf("variable %s is already defined in this scope", name)
+		}
+	}
+
+	return s.Define(name), nil
+}
+
+// DefineBuiltin 메서드는 내장 함수를 정의합니다. 인덱스는 내장 함수 표(Bytecode.Builtins)에서의 위치이며,
+// 지역 바인딩 개수(numDefinitions)에는 포함되지 않습니다.
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName 메서드는 현재 함수 자신의 이름을 정의합니다.
+// 재귀 호출이 바인딩이 끝나기 전에도 자기 자신을 참조할 수 있게 해 주며,
+// 지역 바인딩 개수(numDefinitions)에는 포함되지 않습니다.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Resolve 메서드는 이름으로 심볼을 찾습니다. 현재 테이블에 없으면 바깥 스코프를 차례로 찾아보고,
+// 어디에도 정의되지 않은 이름이면 false 를 반환합니다.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		return obj, ok
+	}
+	return obj, ok
+}
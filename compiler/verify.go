@@ -0,0 +1,155 @@
+package compiler
+
+import (
+	"fmt"
+	"monkey/code"
+	"monkey/object"
+)
+
+// Verify 함수는 바이트코드를 VM 에 넘기기 전에 스택 균형을 정적으로 검사합니다.
+// 모든 실행 경로를 따라가며 각 명령어 전후의 스택 깊이를 계산하고, 깊이가 음수가 되거나
+// 분기가 서로 다른 깊이로 합쳐지는 지점이 있으면 에러를 반환합니다.
+// 상수 풀의 컴파일된 함수도 각자 빈 스택에서 시작한다고 보고 함께 검사합니다.
+func Verify(b *Bytecode) error {
+	_, err := analyzeStack(b.Instructions)
+	if err != nil {
+		return err
+	}
+
+	for i, c := range b.Constants {
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		_, err := analyzeStack(fn.Instructions)
+		if err != nil {
+			return fmt.Errorf("constant %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// decodedInstruction 은 검사를 위해 미리 해석해 둔 명령어 하나입니다.
+type decodedInstruction struct {
+	def      *code.Definition
+	op       code.Opcode
+	operands []int
+	next     int // 다음 명령어의 오프셋
+}
+
+// analyzeStack 함수는 명령어 스트림의 모든 실행 경로를 따라가며 스택 깊이를 계산하고,
+// 도달하는 최대 깊이를 반환합니다. 스택 균형이 맞지 않으면 에러를 반환합니다.
+func analyzeStack(ins code.Instructions) (int, error) {
+	decoded := map[int]decodedInstruction{}
+	err := ins.Iterate(func(offset int, def *code.Definition, operands []int) error {
+		op := code.Opcode(ins[offset])
+		length, err := code.InstructionLen(op)
+		if err != nil {
+			return err
+		}
+		decoded[offset] = decodedInstruction{
+			def:      def,
+			op:       op,
+			operands: operands,
+			next:     offset + length,
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	type state struct {
+		pos   int
+		depth int
+	}
+
+	depths := map[int]int{}
+	maxDepth := 0
+	worklist := []state{{pos: 0, depth: 0}}
+
+	for len(worklist) > 0 {
+		s := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		// 스트림의 끝에 도달한 경로는 더 따라갈 것이 없습니다.
+		if s.pos == len(ins) {
+			continue
+		}
+
+		if depth, seen := depths[s.pos]; seen {
+			if depth != s.depth {
+				return 0, fmt.Errorf("stack depth mismatch at %04d: %d vs %d",
+					s.pos, depth, s.depth)
+			}
+			continue
+		}
+		depths[s.pos] = s.depth
+
+		inst := decoded[s.pos]
+		pop, push := stackEffect(inst.op, inst.operands)
+		if s.depth < pop {
+			return 0, fmt.Errorf("stack underflow at %04d: %s needs %d values, stack has %d",
+				s.pos, inst.def.Name, pop, s.depth)
+		}
+		depth := s.depth - pop + push
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		switch inst.op {
+		case code.OpReturnValue, code.OpReturn:
+			// 함수를 빠져나가므로 다음 명령어로 이어지지 않습니다.
+
+		case code.OpJump, code.OpJumpNotTruthy:
+			target := inst.operands[0]
+			if _, ok := decoded[target]; !ok && target != len(ins) {
+				return 0, fmt.Errorf("invalid jump target %d at %04d", target, s.pos)
+			}
+			worklist = append(worklist, state{pos: target, depth: depth})
+
+			if inst.op == code.OpJumpNotTruthy {
+				worklist = append(worklist, state{pos: inst.next, depth: depth})
+			}
+
+		default:
+			worklist = append(worklist, state{pos: inst.next, depth: depth})
+		}
+	}
+
+	return maxDepth, nil
+}
+
+// stackEffect 함수는 명령어가 스택에서 꺼내는 값의 수(pop)와 올려놓는 값의 수(push)를 반환합니다.
+func stackEffect(op code.Opcode, operands []int) (pop, push int) {
+	switch op {
+	case code.OpConstant, code.OpConstantWide, code.OpTrue, code.OpFalse, code.OpNull,
+		code.OpGetGlobal, code.OpGetLocal, code.OpGetBuiltin, code.OpCurrentClosure,
+		code.OpGetLocal0, code.OpGetLocal1, code.OpGetLocal2:
+		return 0, 1
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+		code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterEqual, code.OpIndex:
+		return 2, 1
+	case code.OpMinus, code.OpBang:
+		return 1, 1
+	case code.OpDup:
+		return 1, 2
+	case code.OpPop, code.OpJumpNotTruthy, code.OpSetGlobal, code.OpSetLocal, code.OpReturnValue:
+		return 1, 0
+	case code.OpSetIndex:
+		// 대입한 값을 결과로 남깁니다.
+		return 3, 1
+	case code.OpArray, code.OpHash, code.OpConcatArray:
+		return operands[0], 1
+	case code.OpCall:
+		// 호출할 함수와 인자들을 꺼내고 반환값 하나를 올립니다.
+		return operands[0] + 1, 1
+	case code.OpClosure:
+		// 자유 변수들을 꺼내 클로저 하나로 묶습니다.
+		return operands[1], 1
+	}
+
+	return 0, 0
+}
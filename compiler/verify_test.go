@@ -0,0 +1,128 @@
+package compiler
+
+import (
+	"monkey/code"
+	"testing"
+)
+
+func TestVerifyBalanced(t *testing.T) {
+	inputs := []string{
+		"1 + 2; 3",
+		"if (true) { 10 } else { 20 }; 3333;",
+		"if (true) { 10 };",
+		`let a = [1, 2]; let h = {"a": a[0]}; len(a);`,
+		"let f = fn(x, y) { if (x > y) { return x; } y }; f(1, 2);",
+	}
+
+	for _, input := range inputs {
+		compiler := New()
+		err := compiler.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err = Verify(compiler.Bytecode())
+		if err != nil {
+			t.Errorf("Verify(%q) returned error: %s", input, err)
+		}
+	}
+}
+
+// TestVerifySetIndex는 OpSetIndex 가 대입한 값을 남기므로, 표현식문처럼 뒤에 OpPop 이
+// 오는 스트림이 균형을 이루는지 테스트합니다.
+func TestVerifySetIndex(t *testing.T) {
+	ins := code.Instructions{}
+	for _, in := range []code.Instructions{
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpSetIndex),
+		code.Make(code.OpPop),
+	} {
+		ins = append(ins, in...)
+	}
+
+	depth, err := analyzeStack(ins)
+	if err != nil {
+		t.Fatalf("analyzeStack returned error: %s", err)
+	}
+	if depth != 3 {
+		t.Errorf("wrong depth. want=3, got=%d", depth)
+	}
+}
+
+func TestVerifyCorrupted(t *testing.T) {
+	tests := []struct {
+		instructions []code.Instructions
+		expected     string
+	}{
+		{
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpAdd),
+			},
+			"stack underflow at 0003: OpAdd needs 2 values, stack has 1",
+		},
+		{
+			// 조건이 참인 경로만 상수를 올려놓은 채로 0007 에서 합쳐집니다.
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 7),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+			"stack depth mismatch at 0007: 1 vs 0",
+		},
+		{
+			[]code.Instructions{
+				code.Make(code.OpJump, 2),
+				code.Make(code.OpNull),
+			},
+			"invalid jump target 2 at 0000",
+		},
+	}
+
+	for _, tt := range tests {
+		bytecode := &Bytecode{Instructions: concatInstructions(tt.instructions)}
+
+		err := Verify(bytecode)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestMaxStackDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"1 + 2 * 3", 3},
+		{"1; 2; 3", 1},
+		{"[1, 2, [3, 4, 5]]", 5},
+		// 두 분기 중 더 깊은 쪽을 따릅니다.
+		{"if (true) { 1 + 2 } else { 3 }", 2},
+		{"if (true) { 1 } else { [1, 2, 3] }", 3},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := compiler.Bytecode()
+		if bytecode.MaxStackDepth != tt.expected {
+			t.Errorf("wrong MaxStackDepth for %q. want=%d, got=%d",
+				tt.input, tt.expected, bytecode.MaxStackDepth)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package object
+
+import (
+	"fmt"
+	"monkey/code"
+)
+
+const COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION_OBJ"
+
+// CompiledFunction 은 컴파일러가 함수 리터럴을 컴파일한 결과를 담는 객체입니다.
+// 상수 풀에 저장되어 OpConstant 로 스택에 올라갑니다.
+type CompiledFunction struct {
+	Instructions  code.Instructions // 함수 본문의 바이트코드 명령어
+	NumLocals     int               // 함수가 사용하는 지역 바인딩의 개수
+	NumParameters int               // 함수 매개변수의 개수
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}